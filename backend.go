@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"time"
+)
+
+// Backend is where rendered frames are sent and where key presses come from.
+// SupervisedPanel is the hardware backend; Simulator stands in for it when
+// developing without the appliance.
+type Backend interface {
+	Init() error
+	WriteFrame(fb *image.Gray) error
+	ReadKey(timeout time.Duration) (key byte, ok bool, err error)
+	Close() error
+}
+
+func openBackend(cfg *Config) (Backend, error) {
+	switch cfg.Display {
+	case "", "serial":
+		mode, err := cfg.Serial.Mode()
+		if err != nil {
+			return nil, err
+		}
+		return NewSupervisedPanel(cfg.Serial.Device, mode, time.Duration(cfg.Serial.InitDelay)), nil
+	case "term":
+		return NewTerminalSimulator(os.Stdin, os.Stdout), nil
+	case "png":
+		if cfg.PNGDir == "" {
+			return nil, fmt.Errorf("png display needs an output directory")
+		}
+		return NewPNGSimulator(os.Stdin, cfg.PNGDir, cfg.PNGScale), nil
+	}
+	return nil, fmt.Errorf("unknown display %q (want serial, term or png)", cfg.Display)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot is the state of the system at one sample. Screens read it from
+// the Collector instead of querying the system while drawing.
+type Snapshot struct {
+	Time       time.Time
+	CPU        float64   // percent, over the last interval
+	Cores      []float64 // percent per core, over the last interval
+	Load       [3]float64
+	Temps      []Temperature
+	Mem        MemInfo
+	TopMem     []ProcessMem       // largest RSS first
+	Processes  []ProcessCPU       // busiest first
+	Disks      map[string]FSUsage // by path, for the paths asked for
+	Mounts     []MountUsage
+	Uptime     string
+	Interfaces []NetInterfaceInfo
+}
+
+// ProcessCPU is a process with its CPU usage over the last interval, in
+// percent of one core like top shows it.
+type ProcessCPU struct {
+	ProcStat
+	CPU float64
+}
+
+// MountUsage is a mounted filesystem with its usage and the I/O rates of
+// its device in bytes per second.
+type MountUsage struct {
+	Mount
+	FSUsage
+	ReadRate, WriteRate float64
+}
+
+// Need is a set of samples that are costly to take, so the collector only
+// takes them while a screen that shows them is visible.
+type Need uint
+
+const (
+	NeedProcesses Need = 1 << iota // CPU usage of every process
+	NeedTopMem                     // the largest processes by RSS
+	NeedWireless                   // nl80211 details of wireless interfaces
+)
+
+// Needer is implemented by screens that show costly samples. The main loop
+// passes what the visible screens need to Collector.SetNeeds.
+type Needer interface {
+	Needs() Need
+}
+
+// Collector samples the system on its own goroutine and keeps the latest
+// Snapshot plus a history of the values the graphs show. All methods are
+// safe to call from any goroutine and never block on I/O.
+type Collector struct {
+	size int
+
+	mu    sync.Mutex
+	needs Need
+	snap  Snapshot
+	disks map[string]bool // paths to statfs
+	cpu   *Ring           // percent
+	mem   *Ring           // percent
+	rx    map[string]*Ring
+	tx    map[string]*Ring
+
+	// Previous counters for the deltas, only touched by Run.
+	prevCPU   CPUTimes
+	prevCores []CPUTimes
+	prevTime  time.Time
+	prevNet   map[string][2]uint64
+	prevIO    map[uint64]DiskIO
+	prevProcs map[int]ProcStat
+}
+
+// topProcesses is how many of the largest processes a Snapshot lists.
+const topProcesses = 20
+
+// collector is started from main and read by the screens.
+var collector = NewCollector(120)
+
+// NewCollector keeps the last size samples of every graphed value.
+func NewCollector(size int) *Collector {
+	return &Collector{
+		size:    size,
+		disks:   map[string]bool{"/": true}, // the system screen's default
+		cpu:     NewRing(size),
+		mem:     NewRing(size),
+		rx:      make(map[string]*Ring),
+		tx:      make(map[string]*Ring),
+		prevNet: make(map[string][2]uint64),
+	}
+}
+
+// Run samples every interval until stop is closed.
+func (c *Collector) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	c.sample(time.Now())
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			c.sample(now)
+		}
+	}
+}
+
+// SetNeeds sets which costly samples to take from the next sample on.
+func (c *Collector) SetNeeds(needs Need) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.needs = needs
+}
+
+func (c *Collector) sample(now time.Time) {
+	c.mu.Lock()
+	needs := c.needs
+	c.mu.Unlock()
+	first := c.prevTime.IsZero()
+	dt := now.Sub(c.prevTime).Seconds()
+	c.prevTime = now
+
+	snap := Snapshot{
+		Time:   now,
+		Disks:  make(map[string]FSUsage),
+		Uptime: GetUptime(),
+		Load:   GetLoadAvg(),
+		Temps:  GetTemperatures(),
+	}
+
+	all, cores, ok := GetCPUTimes()
+	if ok {
+		if needs&NeedProcesses != 0 {
+			snap.Processes = c.sampleProcesses(all.Total-c.prevCPU.Total, len(cores))
+		} else {
+			// Usage since a sample long ago would be meaningless.
+			c.prevProcs = nil
+		}
+		snap.CPU = all.Usage(c.prevCPU)
+		snap.Cores = make([]float64, len(cores))
+		for i, core := range cores {
+			// Cores can come and go with CPU hotplug.
+			if i < len(c.prevCores) {
+				snap.Cores[i] = core.Usage(c.prevCores[i])
+			}
+		}
+		c.prevCPU, c.prevCores = all, cores
+	}
+
+	snap.Mem = GetMemInfo()
+	if needs&NeedTopMem != 0 {
+		snap.TopMem = GetTopProcessesByRSS(topProcesses)
+	}
+
+	c.mu.Lock()
+	paths := make([]string, 0, len(c.disks))
+	for path := range c.disks {
+		paths = append(paths, path)
+	}
+	c.mu.Unlock()
+	for _, path := range paths {
+		if usage, err := GetFSUsage(path); err == nil {
+			snap.Disks[path] = usage
+		}
+	}
+
+	diskIO := GetDiskIO()
+	for _, m := range GetMounts() {
+		usage, err := GetFSUsage(m.Path)
+		if err != nil {
+			continue
+		}
+		mu := MountUsage{Mount: m, FSUsage: usage}
+		cur, ok := diskIO[m.Dev]
+		prev, seen := c.prevIO[m.Dev]
+		if ok && seen && dt > 0 && cur.ReadBytes >= prev.ReadBytes && cur.WriteBytes >= prev.WriteBytes {
+			mu.ReadRate = float64(cur.ReadBytes-prev.ReadBytes) / dt
+			mu.WriteRate = float64(cur.WriteBytes-prev.WriteBytes) / dt
+		}
+		snap.Mounts = append(snap.Mounts, mu)
+	}
+	c.prevIO = diskIO
+
+	snap.Interfaces, _ = GetNetworkInterfaces(needs&NeedWireless != 0)
+	seen := make(map[string]bool)
+	for i := range snap.Interfaces {
+		iface := &snap.Interfaces[i]
+		seen[iface.Name] = true
+		prev, ok := c.prevNet[iface.Name]
+		c.prevNet[iface.Name] = [2]uint64{iface.RxBytes, iface.TxBytes}
+		// Counters can go backwards when an interface is recreated.
+		if ok && dt > 0 && iface.RxBytes >= prev[0] && iface.TxBytes >= prev[1] {
+			iface.RxRate = int64(float64(iface.RxBytes-prev[0]) / dt)
+			iface.TxRate = int64(float64(iface.TxBytes-prev[1]) / dt)
+		}
+	}
+	for name := range c.prevNet {
+		if !seen[name] {
+			delete(c.prevNet, name)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snap = snap
+	// The first sample has nothing to compute rates against.
+	if first {
+		return
+	}
+	c.cpu.Push(snap.CPU)
+	mem := 0.0
+	if snap.Mem.Total > 0 {
+		mem = 100 * float64(snap.Mem.Used()) / float64(snap.Mem.Total)
+	}
+	c.mem.Push(mem)
+	for _, iface := range snap.Interfaces {
+		if c.rx[iface.Name] == nil {
+			c.rx[iface.Name] = NewRing(c.size)
+			c.tx[iface.Name] = NewRing(c.size)
+		}
+		c.rx[iface.Name].Push(float64(iface.RxRate))
+		c.tx[iface.Name].Push(float64(iface.TxRate))
+	}
+	for name := range c.rx {
+		if !seen[name] {
+			delete(c.rx, name)
+			delete(c.tx, name)
+		}
+	}
+}
+
+// sampleProcesses works out each process's share of the elapsed CPU time,
+// given as the jiffies that passed on all cores together.
+func (c *Collector) sampleProcesses(elapsed uint64, cores int) []ProcessCPU {
+	stats := GetProcStats()
+	procs := make([]ProcessCPU, len(stats))
+	cur := make(map[int]ProcStat, len(stats))
+	for i, p := range stats {
+		procs[i].ProcStat = p
+		cur[p.PID] = p
+		prev, ok := c.prevProcs[p.PID]
+		if ok && prev.StartTime == p.StartTime && p.CPUTicks >= prev.CPUTicks && elapsed > 0 && cores > 0 {
+			procs[i].CPU = 100 * float64(p.CPUTicks-prev.CPUTicks) / (float64(elapsed) / float64(cores))
+		}
+	}
+	c.prevProcs = cur
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].CPU > procs[j].CPU })
+	return procs
+}
+
+// Snapshot returns the latest sample. It is a copy the caller may keep.
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snap := c.snap
+	snap.Cores = append([]float64(nil), c.snap.Cores...)
+	snap.TopMem = append([]ProcessMem(nil), c.snap.TopMem...)
+	snap.Processes = append([]ProcessCPU(nil), c.snap.Processes...)
+	snap.Temps = append([]Temperature(nil), c.snap.Temps...)
+	snap.Interfaces = append([]NetInterfaceInfo(nil), c.snap.Interfaces...)
+	snap.Mounts = append([]MountUsage(nil), c.snap.Mounts...)
+	snap.Disks = make(map[string]FSUsage, len(c.snap.Disks))
+	for path, d := range c.snap.Disks {
+		snap.Disks[path] = d
+	}
+	return snap
+}
+
+// Disk returns the usage of the filesystem at path. Paths are sampled from
+// the first time they are asked for, so ok is false until the next sample.
+func (c *Collector) Disk(path string) (d FSUsage, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disks[path] = true
+	d, ok = c.snap.Disks[path]
+	return d, ok
+}
+
+// CPU returns CPU usage in percent, oldest first.
+func (c *Collector) CPU() []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cpu.Values()
+}
+
+// Mem returns memory usage in percent, oldest first.
+func (c *Collector) Mem() []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mem.Values()
+}
+
+// Net returns the receive and transmit rates of an interface in bytes per
+// second, oldest first.
+func (c *Collector) Net(name string) (rx, tx []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rx[name] == nil {
+		return nil, nil
+	}
+	return c.rx[name].Values(), c.tx[name].Values()
+}
+
+// Interfaces returns the names of the interfaces with history, sorted.
+func (c *Collector) Interfaces() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.rx))
+	for name := range c.rx {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
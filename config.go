@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultConfigPath = "/etc/lcdinator.conf"
+
+// Config is read from a JSON file (by default /etc/lcdinator.conf) and can be
+// overridden from the command line. Every field is optional:
+//
+//	{
+//	  "serial": {"device": "/dev/ttyS1", "baud_rate": 115200, "data_bits": 8,
+//	             "parity": "none", "stop_bits": "1", "init_delay": "5ms"},
+//	  "refresh": "1s",
+//	  "animation": "200ms",
+//	  "sample": "1s",
+//	  "screens": [
+//	    {"name": "system", "options": {"disk": "/"}},
+//	    {"name": "network", "font": "5x7", "options": {"hide": ["lo"]}}
+//	  ],
+//	  "services": {"pinned": ["nginx.service", "sshd.service"],
+//	               "hide": ["getty@*"], "read_only": ["sshd.service"]},
+//	  "lock": {"pin": "$2a$10$...", "attempts": 3, "lockout": "5m", "relock": "2m"}
+//	}
+type Config struct {
+	Serial    SerialConfig   `json:"serial"`
+	Display   string         `json:"display"`
+	PNGDir    string         `json:"png_dir"`
+	PNGScale  int            `json:"png_scale"`
+	Refresh   Duration       `json:"refresh"`
+	Animation Duration       `json:"animation"` // redraw interval while text scrolls
+	Sample    Duration       `json:"sample"`    // system statistics sampling interval
+	Screens   []ScreenConfig `json:"screens"`
+	Services  ServicesConfig `json:"services"`
+	Lock      LockConfig     `json:"lock"`
+}
+
+type SerialConfig struct {
+	Device    string   `json:"device"`
+	BaudRate  int      `json:"baud_rate"`
+	DataBits  int      `json:"data_bits"`
+	Parity    string   `json:"parity"`    // none, odd, even, mark or space
+	StopBits  string   `json:"stop_bits"` // 1, 1.5 or 2
+	InitDelay Duration `json:"init_delay"`
+}
+
+// ScreenConfig puts a screen in the rotation. Options are decoded by the
+// screen itself, see screenFactories. Font is a built-in font name or the
+// path of a BDF or PCF file; see LoadFace.
+type ScreenConfig struct {
+	Name    string          `json:"name"`
+	Font    string          `json:"font,omitempty"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// ServicesConfig configures the service manager. Pinned services are
+// listed by its Pinned filter, in this order. The rest are glob patterns of
+// service names that make up the policy: only services matching Show and
+// not Hide are listed, and only those also matching Start, Stop or Restart,
+// and not ReadOnly, can be started, stopped or restarted from the panel.
+type ServicesConfig struct {
+	Pinned   []string `json:"pinned"`
+	Show     []string `json:"show"`
+	Hide     []string `json:"hide"`
+	Start    []string `json:"start"`
+	Stop     []string `json:"stop"`
+	Restart  []string `json:"restart"`
+	ReadOnly []string `json:"read_only"`
+}
+
+// Visible reports whether the policy lists the service.
+func (c ServicesConfig) Visible(name string) bool {
+	return matchAny(c.Show, name) && !matchAny(c.Hide, name)
+}
+
+// Allows reports whether the policy lets the panel run action (start, stop
+// or restart) on the service.
+func (c ServicesConfig) Allows(name, action string) bool {
+	if !c.Visible(name) || matchAny(c.ReadOnly, name) {
+		return false
+	}
+	switch action {
+	case "start":
+		return matchAny(c.Start, name)
+	case "stop":
+		return matchAny(c.Stop, name)
+	case "restart":
+		return matchAny(c.Restart, name)
+	}
+	return false
+}
+
+// LockConfig sets up the PIN asked for before destructive actions. PIN is
+// a bcrypt hash as printed by -hash-pin; without one the panel isn't
+// locked. Attempts wrong PINs in a row lock the PIN out for Lockout, and
+// the PIN is asked again after Relock without a key press.
+type LockConfig struct {
+	PIN      string   `json:"pin"`
+	Attempts int      `json:"attempts"`
+	Lockout  Duration `json:"lockout"`
+	Relock   Duration `json:"relock"`
+}
+
+// Duration is a time.Duration written as a string like "1s" or "250ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Serial: SerialConfig{
+			Device:    defaultSerialDevice,
+			BaudRate:  115200,
+			DataBits:  8,
+			Parity:    "none",
+			StopBits:  "1",
+			InitDelay: Duration(5 * time.Millisecond),
+		},
+		Display:   "serial",
+		PNGScale:  4,
+		Refresh:   Duration(time.Second),
+		Animation: Duration(200 * time.Millisecond),
+		Sample:    Duration(time.Second),
+		Screens: []ScreenConfig{
+			{Name: "system"},
+			{Name: "network"},
+			{Name: "graph"},
+			{Name: "cpu"},
+			{Name: "disk"},
+			{Name: "memory"},
+		},
+		Services: ServicesConfig{
+			Show:    []string{"*"},
+			Start:   []string{"*"},
+			Stop:    []string{"*"},
+			Restart: []string{"*"},
+		},
+		Lock: LockConfig{
+			Attempts: 3,
+			Lockout:  Duration(5 * time.Minute),
+			Relock:   Duration(2 * time.Minute),
+		},
+	}
+}
+
+// errHashPIN is returned by LoadConfig when -hash-pin asks for a PIN to be
+// hashed instead of the daemon being run.
+var errHashPIN = errors.New("-hash-pin given")
+
+// LoadConfig builds the configuration from defaults, the config file and
+// command-line flags, in that order of precedence.
+func LoadConfig(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("lcdinator", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to the JSON config file")
+	hashPIN := fs.Bool("hash-pin", false, "read a PIN from standard input, print its hash for lock.pin and exit")
+
+	// Flags are applied on top of the file, so parse into a scratch config
+	// and copy over only the ones that were given.
+	var f Config
+	var screens string
+	fs.StringVar(&f.Serial.Device, "device", "", "serial device of the LCD")
+	fs.IntVar(&f.Serial.BaudRate, "baud", 0, "serial baud rate")
+	fs.IntVar(&f.Serial.DataBits, "databits", 0, "serial data bits")
+	fs.StringVar(&f.Serial.Parity, "parity", "", "serial parity: none, odd, even, mark or space")
+	fs.StringVar(&f.Serial.StopBits, "stopbits", "", "serial stop bits: 1, 1.5 or 2")
+	fs.Var(&f.Serial.InitDelay, "init-delay", "pause between LCD init commands")
+	fs.Var(&f.Refresh, "refresh", "screen refresh interval")
+	fs.Var(&f.Animation, "animation", "redraw interval while text is scrolling")
+	fs.Var(&f.Sample, "sample", "system statistics sampling interval")
+	fs.StringVar(&screens, "screens", "", "comma-separated screens in rotation order, e.g. system,network")
+	fs.StringVar(&f.Display, "display", "", "display backend: serial, term or png")
+	fs.StringVar(&f.PNGDir, "png-dir", "", "output directory for the png display")
+	fs.IntVar(&f.PNGScale, "png-scale", 0, "pixel scale factor for PNG snapshots")
+	fs.Parse(args)
+
+	if *hashPIN {
+		return nil, errHashPIN
+	}
+
+	cfg := DefaultConfig()
+	configSet := false
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "config" {
+			configSet = true
+		}
+	})
+	if err := cfg.load(*configPath, configSet); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "device":
+			cfg.Serial.Device = f.Serial.Device
+		case "baud":
+			cfg.Serial.BaudRate = f.Serial.BaudRate
+		case "databits":
+			cfg.Serial.DataBits = f.Serial.DataBits
+		case "parity":
+			cfg.Serial.Parity = f.Serial.Parity
+		case "stopbits":
+			cfg.Serial.StopBits = f.Serial.StopBits
+		case "init-delay":
+			cfg.Serial.InitDelay = f.Serial.InitDelay
+		case "refresh":
+			cfg.Refresh = f.Refresh
+		case "animation":
+			cfg.Animation = f.Animation
+		case "sample":
+			cfg.Sample = f.Sample
+		case "screens":
+			cfg.Screens = nil
+			for _, name := range strings.Split(screens, ",") {
+				cfg.Screens = append(cfg.Screens, ScreenConfig{Name: strings.TrimSpace(name)})
+			}
+		case "display":
+			cfg.Display = f.Display
+		case "png-dir":
+			cfg.PNGDir = f.PNGDir
+		case "png-scale":
+			cfg.PNGScale = f.PNGScale
+		}
+	})
+	// The device can still be given as the only positional argument.
+	if fs.NArg() > 0 {
+		cfg.Serial.Device = fs.Arg(0)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// load reads path over the current values. A missing file is only an error
+// if it was asked for explicitly.
+func (c *Config) load(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if _, err := c.Serial.Mode(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Serial.Device == "" && (c.Display == "" || c.Display == "serial") {
+		errs = append(errs, fmt.Errorf("serial.device must be set"))
+	}
+	if c.Serial.InitDelay < 0 {
+		errs = append(errs, fmt.Errorf("serial.init_delay must not be negative"))
+	}
+	if c.Refresh <= 0 {
+		errs = append(errs, fmt.Errorf("refresh must be positive"))
+	}
+	if c.Animation <= 0 {
+		errs = append(errs, fmt.Errorf("animation must be positive"))
+	}
+	if c.Sample <= 0 {
+		errs = append(errs, fmt.Errorf("sample must be positive"))
+	}
+	switch c.Display {
+	case "", "serial", "term":
+	case "png":
+		if c.PNGDir == "" {
+			errs = append(errs, fmt.Errorf("png_dir must be set for the png display"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("display %q is not one of serial, term or png", c.Display))
+	}
+	if c.PNGScale < 1 {
+		errs = append(errs, fmt.Errorf("png_scale must be at least 1"))
+	}
+	if len(c.Screens) == 0 {
+		errs = append(errs, fmt.Errorf("screens must list at least one screen"))
+	}
+	seen := make(map[string]bool)
+	for i, sc := range c.Screens {
+		if sc.Name == "services" {
+			errs = append(errs, fmt.Errorf("screens[%d]: the service manager is opened from the menu (ESC), not the rotation", i))
+			continue
+		}
+		if _, ok := screenFactories[sc.Name]; !ok {
+			errs = append(errs, fmt.Errorf("screens[%d]: unknown screen %q (known: %s)", i, sc.Name, strings.Join(screenNames(), ", ")))
+			continue
+		}
+		if seen[sc.Name] {
+			errs = append(errs, fmt.Errorf("screens[%d]: %q listed more than once", i, sc.Name))
+		}
+		seen[sc.Name] = true
+		if _, err := newScreen(nil, sc); err != nil {
+			errs = append(errs, fmt.Errorf("screens[%d] (%s): %w", i, sc.Name, err))
+		}
+	}
+	for i, name := range c.Services.Pinned {
+		if !strings.HasSuffix(name, ".service") {
+			errs = append(errs, fmt.Errorf("services.pinned[%d]: %q is not a .service unit", i, name))
+		}
+	}
+	for _, list := range []struct {
+		key      string
+		patterns []string
+	}{
+		{"show", c.Services.Show},
+		{"hide", c.Services.Hide},
+		{"start", c.Services.Start},
+		{"stop", c.Services.Stop},
+		{"restart", c.Services.Restart},
+		{"read_only", c.Services.ReadOnly},
+	} {
+		for i, pattern := range list.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("services.%s[%d]: bad pattern %q", list.key, i, pattern))
+			}
+		}
+	}
+	if c.Lock.PIN != "" {
+		if _, err := bcrypt.Cost([]byte(c.Lock.PIN)); err != nil {
+			errs = append(errs, fmt.Errorf("lock.pin must be a hash printed by -hash-pin: %w", err))
+		}
+	}
+	if c.Lock.Attempts < 1 {
+		errs = append(errs, fmt.Errorf("lock.attempts must be at least 1"))
+	}
+	if c.Lock.Lockout < 0 {
+		errs = append(errs, fmt.Errorf("lock.lockout must not be negative"))
+	}
+	if c.Lock.Relock <= 0 {
+		errs = append(errs, fmt.Errorf("lock.relock must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Mode converts the serial settings for go.bug.st/serial.
+func (s SerialConfig) Mode() (*serial.Mode, error) {
+	var errs []error
+	mode := &serial.Mode{BaudRate: s.BaudRate, DataBits: s.DataBits}
+	if s.BaudRate <= 0 {
+		errs = append(errs, fmt.Errorf("serial.baud_rate must be positive, got %d", s.BaudRate))
+	}
+	if s.DataBits < 5 || s.DataBits > 8 {
+		errs = append(errs, fmt.Errorf("serial.data_bits must be between 5 and 8, got %d", s.DataBits))
+	}
+	switch strings.ToLower(s.Parity) {
+	case "none", "":
+		mode.Parity = serial.NoParity
+	case "odd":
+		mode.Parity = serial.OddParity
+	case "even":
+		mode.Parity = serial.EvenParity
+	case "mark":
+		mode.Parity = serial.MarkParity
+	case "space":
+		mode.Parity = serial.SpaceParity
+	default:
+		errs = append(errs, fmt.Errorf("serial.parity must be none, odd, even, mark or space, got %q", s.Parity))
+	}
+	switch s.StopBits {
+	case "1", "":
+		mode.StopBits = serial.OneStopBit
+	case "1.5":
+		mode.StopBits = serial.OnePointFiveStopBits
+	case "2":
+		mode.StopBits = serial.TwoStopBits
+	default:
+		errs = append(errs, fmt.Errorf("serial.stop_bits must be 1, 1.5 or 2, got %q", s.StopBits))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return mode, nil
+}
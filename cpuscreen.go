@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// CPUScreen shows the load averages, temperatures and a usage bar per core.
+// With more cores than fit, they are laid out in two columns and UP/DOWN
+// scroll them.
+type CPUScreen struct {
+	screenFace
+	offset      int // first core row shown
+	maxOffset   int // as of the last Draw
+	tempMarquee Marquee
+	animating   bool
+}
+
+func NewCPUScreen() *CPUScreen {
+	s := &CPUScreen{}
+	s.SetFace(defaultFace("5x7"))
+	return s
+}
+
+func (s *CPUScreen) Draw(fb *image.Gray) {
+	face := s.Face()
+	lineHeight := face.Metrics().Height.Ceil() + 1
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	b := fb.Bounds()
+	snap := collector.Snapshot()
+
+	d.Dot = fixed.P(b.Min.X, Baseline(face, b.Min.Y, lineHeight))
+	d.DrawString(fmt.Sprintf("Load %.2f %.2f %.2f", snap.Load[0], snap.Load[1], snap.Load[2]))
+
+	temps := make([]string, len(snap.Temps))
+	for i, t := range snap.Temps {
+		temps[i] = fmt.Sprintf("%.0f°C %s", t.Celsius, t.Name)
+	}
+	tempText := "No temp sensors"
+	if len(temps) > 0 {
+		tempText = strings.Join(temps, ", ")
+	}
+	y := b.Min.Y + lineHeight
+	s.animating = s.tempMarquee.Draw(d, image.Rect(b.Min.X, y, b.Max.X, y+lineHeight), Baseline(face, y, lineHeight), tempText)
+	y += lineHeight + 1
+
+	rows, cols := s.layout(len(snap.Cores), b.Max.Y-y, lineHeight)
+	colWidth := b.Dx() / cols
+	for i := 0; i < rows*cols; i++ {
+		core := s.offset*cols + i
+		if core >= len(snap.Cores) {
+			break
+		}
+		top := y + (i/cols)*lineHeight
+		left := b.Min.X + (i%cols)*colWidth
+		s.drawCore(fb, d, image.Rect(left, top, left+colWidth-3, top+lineHeight), core, snap.Cores[core], cols == 1)
+	}
+}
+
+// layout returns how many rows of how many columns of cores fit in height.
+func (s *CPUScreen) layout(cores, height, lineHeight int) (rows, cols int) {
+	rows = height / lineHeight
+	if rows < 1 {
+		rows = 1
+	}
+	cols = 1
+	if cores > rows {
+		cols = 2
+	}
+	// Keep the scroll position in range as cores come and go.
+	s.maxOffset = (cores+cols-1)/cols - rows
+	if s.maxOffset < 0 {
+		s.maxOffset = 0
+	}
+	if s.offset > s.maxOffset {
+		s.offset = s.maxOffset
+	}
+	return rows, cols
+}
+
+// drawCore draws "N [bar] P%" in r, leaving out the percentage when two
+// columns make the bars too short for it.
+func (s *CPUScreen) drawCore(fb *image.Gray, d *font.Drawer, r image.Rectangle, core int, usage float64, percent bool) {
+	baseline := Baseline(d.Face, r.Min.Y, r.Dy())
+	d.Dot = fixed.P(r.Min.X, baseline)
+	d.DrawString(fmt.Sprint(core))
+	// Leave room for two-digit core numbers and "100%" so bars line up.
+	barLeft := r.Min.X + d.MeasureString("00 ").Ceil()
+	barRight := r.Max.X
+	if percent {
+		barRight -= d.MeasureString(" 100%").Ceil()
+		text := fmt.Sprintf("%3.0f%%", usage)
+		d.Dot = fixed.P(r.Max.X-d.MeasureString(text).Ceil(), baseline)
+		d.DrawString(text)
+	}
+	DrawProgressBar(fb, image.Rect(barLeft, r.Min.Y+1, barRight, r.Max.Y-1), usage/100)
+}
+
+func (s *CPUScreen) Animating() bool {
+	return s.animating
+}
+
+func (s *CPUScreen) HandleKey(key byte) bool {
+	switch key {
+	case KEY_UP:
+		if s.offset > 0 {
+			s.offset--
+			return true
+		}
+	case KEY_DOWN:
+		if s.offset < s.maxOffset {
+			s.offset++
+			return true
+		}
+	}
+	return false
+}
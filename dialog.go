@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Dialog is a modal confirmation box drawn over the screen it was pushed
+// on. LEFT/RIGHT pick a button, ENTER activates it and ESC cancels. The
+// dialog pops itself before running a callback, so callbacks are free to
+// push or pop screens of their own.
+type Dialog struct {
+	Message     string
+	OKLabel     string
+	CancelLabel string
+	OnConfirm   func()
+	OnCancel    func() // optional
+
+	nav       *Navigator
+	selected  int           // 0 = OK, 1 = Cancel
+	timeout   time.Duration // 0 for no timeout
+	deadline  time.Time
+	timer     *time.Timer
+	closed    bool
+	marquee   Marquee // for a message too long for the box
+	animating bool
+}
+
+// NewConfirmDialog asks message with OK/Cancel buttons. Cancel is selected
+// initially so a stray ENTER doesn't trigger anything destructive.
+func NewConfirmDialog(nav *Navigator, message string, onConfirm func()) *Dialog {
+	return &Dialog{
+		Message:     message,
+		OKLabel:     "OK",
+		CancelLabel: "Cancel",
+		OnConfirm:   onConfirm,
+		nav:         nav,
+		selected:    1,
+	}
+}
+
+// NewTimedDialog asks message like NewConfirmDialog, but cancels itself
+// if no choice is made within timeout of being shown. It counts down the
+// seconds left.
+func NewTimedDialog(nav *Navigator, message string, timeout time.Duration, onConfirm, onCancel func()) *Dialog {
+	d := NewConfirmDialog(nav, message, onConfirm)
+	d.OnCancel = onCancel
+	d.timeout = timeout
+	return d
+}
+
+// Cancel closes the dialog as if Cancel had been chosen, wherever it is in
+// the stack. It does nothing if the dialog was already closed.
+func (d *Dialog) Cancel() {
+	if d.close() && d.OnCancel != nil {
+		d.OnCancel()
+	}
+}
+
+// close removes the dialog and reports whether it was still open.
+func (d *Dialog) close() bool {
+	if d.closed {
+		return false
+	}
+	d.closed = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.nav.Remove(d)
+	return true
+}
+
+func (d *Dialog) isOverlay() {}
+
+func (d *Dialog) Animating() bool {
+	return d.animating
+}
+
+func (d *Dialog) Draw(fb *image.Gray) {
+	face := basicfont.Face7x13
+	ascent := face.Metrics().Ascent.Ceil()
+	buttonHeight := face.Metrics().Height.Ceil() + 1
+	if d.timeout > 0 && d.timer == nil && !d.closed {
+		// The first Draw is when the dialog is shown.
+		d.deadline = time.Now().Add(d.timeout)
+		d.timer = time.AfterFunc(d.timeout, func() { d.nav.Do(d.Cancel) })
+	}
+	message := d.Message
+	if !d.deadline.IsZero() {
+		message += fmt.Sprintf(" %ds", int(time.Until(d.deadline).Seconds()+0.5))
+	}
+	box, buttonTop, animating := drawDialogBox(fb, face, message, buttonHeight, &d.marquee)
+	d.animating = animating
+
+	dr := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	labels := []string{d.OKLabel, d.CancelLabel}
+	for i, label := range labels {
+		w := dr.MeasureString(label).Ceil()
+		centre := box.Min.X + box.Dx()*(2*i+1)/4
+		button := image.Rect(centre-w/2-3, buttonTop, centre+w/2+4, buttonTop+buttonHeight)
+		src := image.Black
+		if i == d.selected {
+			FillRect(fb, button, color.Gray{Y: 0})
+			src = image.White
+		} else {
+			DrawRect(fb, button, color.Gray{Y: 0})
+		}
+		dr.Src = src
+		dr.Dot = fixed.P(button.Min.X+3, button.Min.Y+ascent)
+		dr.DrawString(label)
+	}
+}
+
+// drawDialogBox draws a bordered box centred on fb with message wrapped
+// inside it and room for a footer row of footerHeight pixels below. When
+// the message has more lines than fit, the rest of it scrolls by in the
+// last line with marquee, and animating is true. It returns the box and
+// the top of the footer.
+func drawDialogBox(fb *image.Gray, face font.Face, message string, footerHeight int, marquee *Marquee) (box image.Rectangle, footerTop int, animating bool) {
+	m := face.Metrics()
+	lineHeight := m.Height.Ceil()
+	ascent := m.Ascent.Ceil()
+
+	const margin = 2  // between the screen edge and the box
+	const padding = 3 // between the border and the contents
+	bounds := fb.Bounds()
+	boxWidth := bounds.Dx() - 2*margin
+	textWidth := boxWidth - 2*padding - 2
+
+	maxLines := (bounds.Dy() - 2*margin - 2*padding - 2 - (footerHeight + 2)) / lineHeight
+	lines := WrapText(face, message, textWidth)
+	overflow := ""
+	if len(lines) > maxLines {
+		overflow = remainder(message, lines[:maxLines-1])
+		lines = lines[:maxLines]
+	}
+
+	boxHeight := 2 + 2*padding + len(lines)*lineHeight + 2 + footerHeight
+	box = image.Rect(margin, 0, margin+boxWidth, boxHeight).Add(image.Pt(bounds.Min.X, bounds.Min.Y+(bounds.Dy()-boxHeight)/2))
+
+	FillRect(fb, box, color.Gray{Y: 255})
+	DrawRect(fb, box, color.Gray{Y: 0})
+
+	dr := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	y := box.Min.Y + 1 + padding
+	for i, line := range lines {
+		if i == maxLines-1 && overflow != "" {
+			left := box.Min.X + 1 + padding
+			animating = marquee.Draw(dr, image.Rect(left, y, left+textWidth, y+lineHeight), y+ascent, overflow)
+			break
+		}
+		w := dr.MeasureString(line).Ceil()
+		dr.Dot = fixed.P(box.Min.X+(box.Dx()-w)/2, y+ascent)
+		dr.DrawString(line)
+		y += lineHeight
+	}
+	return box, box.Max.Y - 1 - padding - footerHeight, animating
+}
+
+// remainder returns what is left of message after the words, or pieces of
+// words, that WrapText put on lines.
+func remainder(message string, lines []string) string {
+	rest := message
+	for _, line := range lines {
+		for _, word := range strings.Fields(line) {
+			rest = strings.TrimPrefix(strings.TrimLeft(rest, " \t\n"), word)
+		}
+	}
+	return strings.TrimSpace(rest)
+}
+
+func (d *Dialog) HandleKey(key byte) bool {
+	switch key {
+	case KEY_LEFT:
+		d.selected = 0
+		return true
+	case KEY_RIGHT:
+		d.selected = 1
+		return true
+	case KEY_ENTER:
+		if d.selected == 1 {
+			d.Cancel()
+		} else if d.close() && d.OnConfirm != nil {
+			d.OnConfirm()
+		}
+		return true
+	case KEY_ESC:
+		d.Cancel()
+		return true
+	}
+	// Swallow everything else so it doesn't reach the screen underneath.
+	return true
+}
+
+// ValueDialog asks for a number between Min and Max. UP/DOWN change it,
+// ENTER confirms and ESC cancels. Like Dialog it pops itself first and
+// swallows the other keys.
+type ValueDialog struct {
+	Message   string
+	Value     int
+	Min, Max  int
+	OnConfirm func(value int)
+
+	nav       *Navigator
+	marquee   Marquee
+	animating bool
+}
+
+func NewValueDialog(nav *Navigator, message string, value, min, max int, onConfirm func(int)) *ValueDialog {
+	return &ValueDialog{Message: message, Value: value, Min: min, Max: max, OnConfirm: onConfirm, nav: nav}
+}
+
+func (d *ValueDialog) isOverlay() {}
+
+func (d *ValueDialog) Animating() bool {
+	return d.animating
+}
+
+func (d *ValueDialog) Draw(fb *image.Gray) {
+	face := basicfont.Face7x13
+	height := face.Metrics().Height.Ceil() + 1
+	box, top, animating := drawDialogBox(fb, face, d.Message, height, &d.marquee)
+	d.animating = animating
+
+	dr := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	text := fmt.Sprintf("- %d +", d.Value)
+	w := dr.MeasureString(text).Ceil()
+	dr.Dot = fixed.P(box.Min.X+(box.Dx()-w)/2, top+face.Metrics().Ascent.Ceil())
+	dr.DrawString(text)
+}
+
+func (d *ValueDialog) HandleKey(key byte) bool {
+	switch key {
+	case KEY_UP:
+		if d.Value < d.Max {
+			d.Value++
+		}
+	case KEY_DOWN:
+		if d.Value > d.Min {
+			d.Value--
+		}
+	case KEY_ENTER:
+		d.nav.Pop()
+		if d.OnConfirm != nil {
+			d.OnConfirm(d.Value)
+		}
+	case KEY_ESC:
+		d.nav.Pop()
+	}
+	return true
+}
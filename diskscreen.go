@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// DiskScreen shows one mounted filesystem at a time: its size, space and
+// inode usage, and the read/write rates of its device. UP/DOWN change the
+// filesystem.
+type DiskScreen struct {
+	Hide []string `json:"hide"` // mount points or glob patterns to leave out
+
+	screenFace
+	index     int
+	marquee   Marquee
+	animating bool
+}
+
+func NewDiskScreen() *DiskScreen {
+	s := &DiskScreen{}
+	s.SetFace(defaultFace("5x7"))
+	return s
+}
+
+// mounts returns the filesystems to show, without the hidden ones.
+func (s *DiskScreen) mounts() []MountUsage {
+	mounts := collector.Snapshot().Mounts
+	shown := mounts[:0]
+	for _, m := range mounts {
+		if !matchAny(s.Hide, m.Path) {
+			shown = append(shown, m)
+		}
+	}
+	return shown
+}
+
+func (s *DiskScreen) Draw(fb *image.Gray) {
+	face := s.Face()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	b := fb.Bounds()
+	mounts := s.mounts()
+	s.animating = false
+	if len(mounts) == 0 {
+		d.Dot = fixed.P(b.Min.X, Baseline(face, b.Min.Y, 16))
+		d.DrawString("No filesystems")
+		return
+	}
+	if s.index >= len(mounts) {
+		s.index = 0
+	}
+	m := mounts[s.index]
+
+	// Five rows: header, size, space bar, inode bar, I/O rates.
+	rowHeight := b.Dy() / 5
+	row := func(i int) image.Rectangle {
+		top := b.Min.Y + i*rowHeight
+		return image.Rect(b.Min.X, top, b.Max.X, top+rowHeight)
+	}
+	text := func(i int, str string) {
+		d.Dot = fixed.P(b.Min.X, Baseline(face, row(i).Min.Y, rowHeight))
+		d.DrawString(str)
+	}
+
+	header := fmt.Sprintf("%s (%d/%d) %s %s", m.Path, s.index+1, len(mounts), strings.TrimPrefix(m.Device, "/dev/"), m.FSType)
+	s.animating = s.marquee.Draw(d, row(0), Baseline(face, row(0).Min.Y, rowHeight), header)
+	text(1, fmt.Sprintf("%s, %s free", formatBytes(m.Total), formatBytes(m.Avail)))
+	s.drawBar(fb, d, row(2), "Space", m.Used, m.Total)
+	s.drawBar(fb, d, row(3), "Inode", m.InodesUsed, m.Inodes)
+	text(4, fmt.Sprintf("R %s W %s", formatRate(m.ReadRate), formatRate(m.WriteRate)))
+}
+
+// drawBar draws a gauge of used out of total. Filesystems without a fixed
+// number of inodes, like btrfs, report 0 total.
+func (s *DiskScreen) drawBar(fb *image.Gray, d *font.Drawer, r image.Rectangle, label string, used, total uint64) {
+	frac := -1.0
+	if total > 0 {
+		frac = float64(used) / float64(total)
+	}
+	DrawGauge(fb, d, r, label, d.MeasureString("Space ").Ceil(), frac)
+}
+
+func (s *DiskScreen) Animating() bool {
+	return s.animating
+}
+
+func (s *DiskScreen) HandleKey(key byte) bool {
+	n := len(s.mounts())
+	if n == 0 {
+		return false
+	}
+	switch key {
+	case KEY_UP:
+		s.index = (s.index + n - 1) % n
+	case KEY_DOWN:
+		s.index = (s.index + 1) % n
+	default:
+		return false
+	}
+	s.marquee.Reset()
+	return true
+}
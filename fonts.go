@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+//go:embed fonts/*.bdf
+var embeddedFonts embed.FS
+
+// BitmapFont is a font.Face for fixed-size bitmap fonts loaded from BDF or
+// PCF files. Glyphs are drawn at whole pixel positions.
+type BitmapFont struct {
+	ascent, descent int
+	glyphs          map[rune]*bitmapGlyph
+	fallback        *bitmapGlyph // drawn for runes the font doesn't have
+}
+
+type bitmapGlyph struct {
+	advance int
+	bounds  image.Rectangle // relative to the dot
+	mask    *image.Alpha    // bounds.Size(), origin at (0, 0)
+}
+
+// LoadFont reads a BDF or PCF font file. PCF files may be gzipped, as they
+// usually are under /usr/share/fonts.
+func LoadFont(path string) (*BitmapFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFont parses a BDF or PCF font, telling them apart by content.
+func ParseFont(data []byte) (*BitmapFont, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("\x01fcp")):
+		return ParsePCF(data)
+	case bytes.HasPrefix(data, []byte("STARTFONT")):
+		return ParseBDF(bytes.NewReader(data))
+	}
+	return nil, errors.New("not a BDF or PCF font")
+}
+
+// ParseBDF parses a font in the Glyph Bitmap Distribution Format.
+func ParseBDF(r io.Reader) (*BitmapFont, error) {
+	f := &BitmapFont{glyphs: make(map[rune]*bitmapGlyph)}
+	defaultChar := rune(-1)
+	var bbox image.Rectangle // FONTBOUNDINGBOX, in image coordinates
+
+	sc := bufio.NewScanner(r)
+	line := 0
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+	ints := func(fields []string, n int) ([]int, bool) {
+		if len(fields) < n+1 {
+			return nil, false
+		}
+		v := make([]int, n)
+		for i := range v {
+			x, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, false
+			}
+			v[i] = x
+		}
+		return v, true
+	}
+
+	var (
+		g        *bitmapGlyph
+		enc      rune
+		inBitmap bool
+		row      int
+	)
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if inBitmap && fields[0] != "ENDCHAR" {
+			if row >= g.bounds.Dy() {
+				continue
+			}
+			b, err := hex.DecodeString(fields[0])
+			if err != nil {
+				return nil, fail("bad bitmap row %q", fields[0])
+			}
+			setRow(g.mask, row, b)
+			row++
+			continue
+		}
+		switch fields[0] {
+		case "FONTBOUNDINGBOX":
+			v, ok := ints(fields, 4)
+			if !ok {
+				return nil, fail("bad FONTBOUNDINGBOX")
+			}
+			bbox = image.Rect(v[2], -v[3]-v[1], v[2]+v[0], -v[3])
+		case "FONT_ASCENT", "FONT_DESCENT", "DEFAULT_CHAR":
+			v, ok := ints(fields, 1)
+			if !ok {
+				return nil, fail("bad %s", fields[0])
+			}
+			switch fields[0] {
+			case "FONT_ASCENT":
+				f.ascent = v[0]
+			case "FONT_DESCENT":
+				f.descent = v[0]
+			default:
+				defaultChar = rune(v[0])
+			}
+		case "STARTCHAR":
+			g = &bitmapGlyph{bounds: bbox, advance: bbox.Dx()}
+			enc = -1
+		case "ENCODING":
+			v, ok := ints(fields, 1)
+			if !ok || g == nil {
+				return nil, fail("bad ENCODING")
+			}
+			enc = rune(v[0])
+		case "DWIDTH":
+			v, ok := ints(fields, 1)
+			if !ok || g == nil {
+				return nil, fail("bad DWIDTH")
+			}
+			g.advance = v[0]
+		case "BBX":
+			v, ok := ints(fields, 4)
+			if !ok || g == nil || v[0] < 0 || v[1] < 0 {
+				return nil, fail("bad BBX")
+			}
+			g.bounds = image.Rect(v[2], -v[3]-v[1], v[2]+v[0], -v[3])
+		case "BITMAP":
+			if g == nil {
+				return nil, fail("BITMAP outside a glyph")
+			}
+			g.mask = image.NewAlpha(image.Rect(0, 0, g.bounds.Dx(), g.bounds.Dy()))
+			inBitmap = true
+			row = 0
+		case "ENDCHAR":
+			if g == nil || g.mask == nil {
+				return nil, fail("ENDCHAR without a bitmap")
+			}
+			// Glyphs with ENCODING -1 have no code point to be looked up by.
+			if enc >= 0 {
+				f.glyphs[enc] = g
+			}
+			g, inBitmap = nil, false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(f.glyphs) == 0 {
+		return nil, errors.New("no glyphs")
+	}
+	if f.ascent == 0 && f.descent == 0 {
+		f.ascent, f.descent = -bbox.Min.Y, bbox.Max.Y
+	}
+	f.setFallback(defaultChar)
+	return f, nil
+}
+
+// PCF table types and format flags, from the X11 font library.
+const (
+	pcfAccelerators    = 1 << 1
+	pcfMetrics         = 1 << 2
+	pcfBitmaps         = 1 << 3
+	pcfBDFEncodings    = 1 << 5
+	pcfBDFAccelerators = 1 << 8
+
+	pcfFormatMask        = 0xffffff00
+	pcfCompressedMetrics = 0x00000100
+	pcfByteMSBFirst      = 1 << 2
+	pcfBitMSBFirst       = 1 << 3
+)
+
+// pcfTable reads the values of one PCF table in the byte order its format
+// word says.
+type pcfTable struct {
+	format uint32
+	order  binary.ByteOrder
+	data   []byte
+	pos    int
+	err    error
+}
+
+func (t *pcfTable) next(n int) []byte {
+	if t.err != nil {
+		return make([]byte, n)
+	}
+	if t.pos+n > len(t.data) {
+		t.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := t.data[t.pos : t.pos+n]
+	t.pos += n
+	return b
+}
+
+func (t *pcfTable) u8() int          { return int(t.next(1)[0]) }
+func (t *pcfTable) i16() int         { return int(int16(t.order.Uint16(t.next(2)))) }
+func (t *pcfTable) u16() int         { return int(t.order.Uint16(t.next(2))) }
+func (t *pcfTable) i32() int         { return int(int32(t.order.Uint32(t.next(4)))) }
+func (t *pcfTable) skip(n int)       { t.next(n) }
+func (t *pcfTable) is(f uint32) bool { return t.format&pcfFormatMask == f }
+
+// ParsePCF parses a font in the X11 Portable Compiled Format.
+func ParsePCF(data []byte) (*BitmapFont, error) {
+	if len(data) < 8 || string(data[:4]) != "\x01fcp" {
+		return nil, errors.New("not a PCF font")
+	}
+	n := int(binary.LittleEndian.Uint32(data[4:]))
+	tables := make(map[uint32]*pcfTable)
+	for i := 0; i < n; i++ {
+		off := 8 + 16*i
+		if off+16 > len(data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		typ := binary.LittleEndian.Uint32(data[off:])
+		size := int(binary.LittleEndian.Uint32(data[off+8:]))
+		start := int(binary.LittleEndian.Uint32(data[off+12:]))
+		if start < 0 || size < 4 || start+size > len(data) {
+			return nil, fmt.Errorf("table %#x out of range", typ)
+		}
+		t := &pcfTable{data: data[start : start+size], order: binary.LittleEndian}
+		// The format word itself is always little-endian.
+		t.format = uint32(t.i32())
+		if t.format&pcfByteMSBFirst != 0 {
+			t.order = binary.BigEndian
+		}
+		tables[typ] = t
+	}
+	for _, typ := range []uint32{pcfMetrics, pcfBitmaps, pcfBDFEncodings} {
+		if tables[typ] == nil {
+			return nil, fmt.Errorf("missing table %#x", typ)
+		}
+	}
+
+	// Metrics.
+	type metric struct{ left, right, width, ascent, descent int }
+	t := tables[pcfMetrics]
+	var metrics []metric
+	compressed := t.is(pcfCompressedMetrics)
+	count := 0
+	if compressed {
+		count = t.i16()
+	} else {
+		count = t.i32()
+	}
+	if count < 0 || count > len(t.data) {
+		return nil, errors.New("metrics: bad glyph count")
+	}
+	metrics = make([]metric, count)
+	if compressed {
+		for i := range metrics {
+			metrics[i] = metric{t.u8() - 0x80, t.u8() - 0x80, t.u8() - 0x80, t.u8() - 0x80, t.u8() - 0x80}
+		}
+	} else {
+		for i := range metrics {
+			metrics[i] = metric{t.i16(), t.i16(), t.i16(), t.i16(), t.i16()}
+			t.skip(2) // attributes
+		}
+	}
+	if t.err != nil {
+		return nil, fmt.Errorf("metrics: %w", t.err)
+	}
+
+	// Bitmaps, converted to most significant bit and byte first.
+	t = tables[pcfBitmaps]
+	if count := t.i32(); count != len(metrics) {
+		return nil, fmt.Errorf("bitmaps: %d bitmaps for %d glyphs", count, len(metrics))
+	}
+	offsets := make([]int, len(metrics))
+	for i := range offsets {
+		offsets[i] = t.i32()
+	}
+	var sizes [4]int
+	for i := range sizes {
+		sizes[i] = t.i32()
+	}
+	pad := 1 << (t.format & 3)
+	unit := 1 << ((t.format >> 4) & 3)
+	bitmaps := t.next(sizes[t.format&3])
+	if t.err != nil {
+		return nil, fmt.Errorf("bitmaps: %w", t.err)
+	}
+	bitmaps = append([]byte(nil), bitmaps...)
+	if t.format&pcfBitMSBFirst == 0 {
+		for i, b := range bitmaps {
+			bitmaps[i] = bits.Reverse8(b)
+		}
+	}
+	if (t.format&pcfByteMSBFirst == 0) != (t.format&pcfBitMSBFirst == 0) && unit > 1 {
+		for i := 0; i+unit <= len(bitmaps); i += unit {
+			u := bitmaps[i : i+unit]
+			for a, b := 0, unit-1; a < b; a, b = a+1, b-1 {
+				u[a], u[b] = u[b], u[a]
+			}
+		}
+	}
+
+	glyphs := make([]*bitmapGlyph, len(metrics))
+	for i, m := range metrics {
+		w, h := m.right-m.left, m.ascent+m.descent
+		if w < 0 || h < 0 {
+			return nil, fmt.Errorf("glyph %d: bad metrics", i)
+		}
+		g := &bitmapGlyph{
+			advance: m.width,
+			bounds:  image.Rect(m.left, -m.ascent, m.right, m.descent),
+			mask:    image.NewAlpha(image.Rect(0, 0, w, h)),
+		}
+		stride := ((w+7)/8 + pad - 1) / pad * pad
+		for y := 0; y < h; y++ {
+			start := offsets[i] + y*stride
+			if start < 0 || start+stride > len(bitmaps) {
+				return nil, fmt.Errorf("glyph %d: bitmap out of range", i)
+			}
+			setRow(g.mask, y, bitmaps[start:start+stride])
+		}
+		glyphs[i] = g
+	}
+
+	// Encodings.
+	f := &BitmapFont{glyphs: make(map[rune]*bitmapGlyph)}
+	t = tables[pcfBDFEncodings]
+	minCol, maxCol := t.i16(), t.i16()
+	minRow, maxRow := t.i16(), t.i16()
+	defaultChar := rune(t.i16())
+	cols, rows := maxCol-minCol+1, maxRow-minRow+1
+	if t.err != nil || cols <= 0 || rows <= 0 || t.pos+2*cols*rows > len(t.data) {
+		return nil, errors.New("encodings: bad table")
+	}
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			idx := t.u16()
+			if idx == 0xffff || idx >= len(glyphs) {
+				continue
+			}
+			f.glyphs[rune(row<<8|col)] = glyphs[idx]
+		}
+	}
+
+	// Font ascent and descent come from the accelerators.
+	if t = tables[pcfBDFAccelerators]; t == nil {
+		t = tables[pcfAccelerators]
+	}
+	if t != nil {
+		t.skip(8) // flags
+		f.ascent, f.descent = t.i32(), t.i32()
+	}
+	if t == nil || t.err != nil {
+		f.ascent, f.descent = 0, 0
+		for _, m := range metrics {
+			if m.ascent > f.ascent {
+				f.ascent = m.ascent
+			}
+			if m.descent > f.descent {
+				f.descent = m.descent
+			}
+		}
+	}
+	f.setFallback(defaultChar)
+	return f, nil
+}
+
+// setRow sets row y of mask from a packed bitmap row, most significant bit
+// first.
+func setRow(mask *image.Alpha, y int, b []byte) {
+	w := mask.Rect.Dx()
+	for x := 0; x < w && x/8 < len(b); x++ {
+		if b[x/8]&(0x80>>(x%8)) != 0 {
+			mask.Pix[y*mask.Stride+x] = 0xff
+		}
+	}
+}
+
+func (f *BitmapFont) setFallback(defaultChar rune) {
+	for _, r := range []rune{defaultChar, '?', ' '} {
+		if g, ok := f.glyphs[r]; ok {
+			f.fallback = g
+			return
+		}
+	}
+}
+
+func (f *BitmapFont) glyph(r rune) (*bitmapGlyph, bool) {
+	if g, ok := f.glyphs[r]; ok {
+		return g, true
+	}
+	return f.fallback, false
+}
+
+func (f *BitmapFont) Close() error { return nil }
+
+func (f *BitmapFont) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+	g, ok := f.glyph(r)
+	if g == nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	dr = g.bounds.Add(image.Pt(dot.X.Round(), dot.Y.Round()))
+	return dr, g.mask, image.Point{}, fixed.I(g.advance), ok
+}
+
+func (f *BitmapFont) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	g, ok := f.glyph(r)
+	if g == nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	bounds = fixed.R(g.bounds.Min.X, g.bounds.Min.Y, g.bounds.Max.X, g.bounds.Max.Y)
+	return bounds, fixed.I(g.advance), ok
+}
+
+func (f *BitmapFont) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	g, ok := f.glyph(r)
+	if g == nil {
+		return 0, false
+	}
+	return fixed.I(g.advance), ok
+}
+
+func (f *BitmapFont) Kern(r0, r1 rune) fixed.Int26_6 { return 0 }
+
+func (f *BitmapFont) Metrics() font.Metrics {
+	m := font.Metrics{
+		Height:  fixed.I(f.ascent + f.descent),
+		Ascent:  fixed.I(f.ascent),
+		Descent: fixed.I(f.descent),
+	}
+	if g, ok := f.glyphs['x']; ok {
+		m.XHeight = fixed.I(-g.bounds.Min.Y)
+	}
+	if g, ok := f.glyphs['H']; ok {
+		m.CapHeight = fixed.I(-g.bounds.Min.Y)
+	}
+	return m
+}
+
+// builtinFaces are the fonts that need no files: the embedded BDF fonts,
+// by file name without the extension, and basicfont's 7x13.
+var (
+	facesMu sync.Mutex
+	faces   = map[string]font.Face{"7x13": basicfont.Face7x13}
+)
+
+// LoadFace returns the font named by a built-in font name ("5x7",
+// "digits16", "7x13") or a path to a BDF or PCF file. Faces are cached, so
+// screens asking for the same font share it.
+func LoadFace(name string) (font.Face, error) {
+	facesMu.Lock()
+	defer facesMu.Unlock()
+	if face, ok := faces[name]; ok {
+		return face, nil
+	}
+	var f *BitmapFont
+	data, err := embeddedFonts.ReadFile("fonts/" + name + ".bdf")
+	if err == nil {
+		f, err = ParseFont(data)
+	} else if strings.ContainsRune(name, os.PathSeparator) || strings.Contains(name, ".") {
+		f, err = LoadFont(name)
+	} else {
+		err = fmt.Errorf("unknown font %q, want a file or one of %s", name, strings.Join(fontNames(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	faces[name] = f
+	return f, nil
+}
+
+// defaultFace returns the built-in font name for a screen that uses it by
+// default. If it can't be loaded, which only a broken build would cause,
+// the error is logged and basicfont.Face7x13 is used instead.
+func defaultFace(name string) font.Face {
+	face, err := LoadFace(name)
+	if err != nil {
+		log.Printf("Font %s: %v", name, err)
+		return basicfont.Face7x13
+	}
+	return face
+}
+
+// fontNames lists the built-in fonts.
+func fontNames() []string {
+	names := []string{"7x13"}
+	entries, _ := embeddedFonts.ReadDir("fonts")
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".bdf"))
+	}
+	return names
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+// testGlyph is a glyph of the test fonts, with its bitmap as rows of '#'
+// and '.'.
+type testGlyph struct {
+	enc                rune
+	left, right, width int
+	ascent, descent    int
+	rows               []string
+}
+
+// testGlyphs are the glyphs of tinyBDF and of the PCF fonts built by
+// buildPCF. W is wider than a byte, so that rows span scan units.
+var testGlyphs = []testGlyph{
+	{enc: '?', left: 0, right: 3, width: 4, ascent: 3, descent: 0, rows: []string{
+		"##.",
+		"..#",
+		".#.",
+	}},
+	{enc: 'A', left: 0, right: 3, width: 4, ascent: 3, descent: 1, rows: []string{
+		".#.",
+		"#.#",
+		"###",
+		"#.#",
+	}},
+	{enc: 'W', left: 1, right: 13, width: 14, ascent: 1, descent: 1, rows: []string{
+		"##..#....###",
+		"#.#.#.#.#..#",
+	}},
+	{enc: '€', left: 0, right: 2, width: 3, ascent: 2, descent: 0, rows: []string{
+		"#.",
+		".#",
+	}},
+}
+
+const tinyBDF = `STARTFONT 2.1
+FONTBOUNDINGBOX 13 4 0 -1
+STARTPROPERTIES 3
+FONT_ASCENT 3
+FONT_DESCENT 1
+DEFAULT_CHAR 63
+ENDPROPERTIES
+CHARS 5
+STARTCHAR question
+ENCODING 63
+DWIDTH 4 0
+BBX 3 3 0 0
+BITMAP
+C0
+20
+40
+ENDCHAR
+STARTCHAR A
+ENCODING 65
+DWIDTH 4 0
+BBX 3 4 0 -1
+BITMAP
+40
+A0
+E0
+A0
+ENDCHAR
+STARTCHAR W
+ENCODING 87
+DWIDTH 14 0
+BBX 12 2 1 -1
+BITMAP
+C870
+AA90
+ENDCHAR
+STARTCHAR Euro
+ENCODING 8364
+DWIDTH 3 0
+BBX 2 2 0 0
+BITMAP
+80
+40
+ENDCHAR
+STARTCHAR unencoded
+ENCODING -1
+DWIDTH 4 0
+BBX 3 1 0 0
+BITMAP
+E0
+ENDCHAR
+ENDFONT
+`
+
+// maskRows returns the mask of g as rows of '#' and '.'.
+func maskRows(g *bitmapGlyph) []string {
+	var rows []string
+	b := g.mask.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		var sb strings.Builder
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if g.mask.AlphaAt(x, y).A != 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		rows = append(rows, sb.String())
+	}
+	return rows
+}
+
+// checkFont compares f with testGlyphs.
+func checkFont(t *testing.T, f *BitmapFont) {
+	t.Helper()
+	if m := f.Metrics(); m.Ascent.Ceil() != 3 || m.Descent.Ceil() != 1 || m.Height.Ceil() != 4 {
+		t.Errorf("metrics: ascent %d, descent %d, height %d, want 3, 1, 4", m.Ascent.Ceil(), m.Descent.Ceil(), m.Height.Ceil())
+	}
+	for _, want := range testGlyphs {
+		g, ok := f.glyph(want.enc)
+		if !ok {
+			t.Errorf("%q: missing", want.enc)
+			continue
+		}
+		if g.advance != want.width {
+			t.Errorf("%q: advance %d, want %d", want.enc, g.advance, want.width)
+		}
+		if r := image.Rect(want.left, -want.ascent, want.right, want.descent); g.bounds != r {
+			t.Errorf("%q: bounds %v, want %v", want.enc, g.bounds, r)
+		}
+		if got := maskRows(g); strings.Join(got, "\n") != strings.Join(want.rows, "\n") {
+			t.Errorf("%q: bitmap\n%s\nwant\n%s", want.enc, strings.Join(got, "\n"), strings.Join(want.rows, "\n"))
+		}
+	}
+	// Missing runes draw the default character but aren't reported as
+	// found.
+	if g, ok := f.glyph('x'); ok || g != f.glyphs['?'] {
+		t.Errorf("'x': got %v, %v, want the '?' glyph and false", g, ok)
+	}
+}
+
+func TestParseBDF(t *testing.T) {
+	f, err := ParseFont([]byte(tinyBDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFont(t, f)
+	if len(f.glyphs) != len(testGlyphs) {
+		t.Errorf("%d glyphs, want %d; the unencoded one must be left out", len(f.glyphs), len(testGlyphs))
+	}
+}
+
+func TestParseBDFErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, old, new, err string
+	}{
+		{"bad bounding box", "FONTBOUNDINGBOX 13 4 0 -1", "FONTBOUNDINGBOX 13 4", "line 2: bad FONTBOUNDINGBOX"},
+		{"bad ascent", "FONT_ASCENT 3", "FONT_ASCENT x", "line 4: bad FONT_ASCENT"},
+		{"bad encoding", "ENCODING 65", "ENCODING", "bad ENCODING"},
+		{"negative BBX", "BBX 3 4 0 -1", "BBX -3 4 0 -1", "bad BBX"},
+		{"bad bitmap row", "A0\nE0", "A0\nZZ", `bad bitmap row "ZZ"`},
+		{"ENDCHAR without bitmap", "BITMAP\n40\nA0\nE0\nA0\n", "", "ENDCHAR without a bitmap"},
+		{"no glyphs", tinyBDF[strings.Index(tinyBDF, "STARTCHAR"):], "ENDFONT\n", "no glyphs"},
+	} {
+		data := strings.Replace(tinyBDF, tt.old, tt.new, 1)
+		if data == tinyBDF {
+			t.Fatalf("%s: fixture not changed", tt.name)
+		}
+		_, err := ParseBDF(strings.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := ParseFont([]byte("hello")); err == nil {
+		t.Error("ParseFont of neither BDF nor PCF: no error")
+	}
+}
+
+// pcfFormat describes how buildPCF lays out a PCF font.
+type pcfFormat struct {
+	msByte, msBit bool
+	pad, unit     int // bytes; 1, 2 or 4
+	compressed    bool
+	accelerators  bool
+}
+
+// buildPCF encodes testGlyphs as a PCF font, the inverse of ParsePCF.
+func buildPCF(pf pcfFormat) []byte {
+	format := uint32(bits.TrailingZeros(uint(pf.pad))) | uint32(bits.TrailingZeros(uint(pf.unit)))<<4
+	var order binary.ByteOrder = binary.LittleEndian
+	if pf.msByte {
+		format |= pcfByteMSBFirst
+		order = binary.BigEndian
+	}
+	if pf.msBit {
+		format |= pcfBitMSBFirst
+	}
+	put := func(b *bytes.Buffer, vs ...any) {
+		for _, v := range vs {
+			binary.Write(b, order, v)
+		}
+	}
+	table := func(format uint32) *bytes.Buffer {
+		b := new(bytes.Buffer)
+		binary.Write(b, binary.LittleEndian, format)
+		return b
+	}
+
+	var metrics *bytes.Buffer
+	if pf.compressed {
+		metrics = table(format | pcfCompressedMetrics)
+		put(metrics, int16(len(testGlyphs)))
+		for _, g := range testGlyphs {
+			for _, v := range []int{g.left, g.right, g.width, g.ascent, g.descent} {
+				metrics.WriteByte(byte(v + 0x80))
+			}
+		}
+	} else {
+		metrics = table(format)
+		put(metrics, int32(len(testGlyphs)))
+		for _, g := range testGlyphs {
+			put(metrics, int16(g.left), int16(g.right), int16(g.width), int16(g.ascent), int16(g.descent), uint16(0))
+		}
+	}
+
+	var data []byte
+	var offsets []int32
+	for _, g := range testGlyphs {
+		offsets = append(offsets, int32(len(data)))
+		stride := ((g.right-g.left+7)/8 + pf.pad - 1) / pf.pad * pf.pad
+		for _, r := range g.rows {
+			row := make([]byte, stride)
+			for x, c := range r {
+				if c == '#' {
+					row[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+			data = append(data, row...)
+		}
+	}
+	if pf.msByte != pf.msBit {
+		for i := 0; i+pf.unit <= len(data); i += pf.unit {
+			u := data[i : i+pf.unit]
+			for a, b := 0, pf.unit-1; a < b; a, b = a+1, b-1 {
+				u[a], u[b] = u[b], u[a]
+			}
+		}
+	}
+	if !pf.msBit {
+		for i, b := range data {
+			data[i] = bits.Reverse8(b)
+		}
+	}
+	bitmaps := table(format)
+	put(bitmaps, int32(len(testGlyphs)), offsets)
+	// Only the size for the padding in use is read.
+	sizes := [4]int32{-1, -1, -1, -1}
+	sizes[format&3] = int32(len(data))
+	put(bitmaps, sizes)
+	bitmaps.Write(data)
+
+	const minCol, maxCol, minRow, maxRow = 0x3f, 0xac, 0x00, 0x20
+	encodings := table(format)
+	put(encodings, int16(minCol), int16(maxCol), int16(minRow), int16(maxRow), int16('?'))
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			idx := uint16(0xffff)
+			for i, g := range testGlyphs {
+				if g.enc == rune(row<<8|col) {
+					idx = uint16(i)
+				}
+			}
+			put(encodings, idx)
+		}
+	}
+
+	tables := []struct {
+		typ uint32
+		b   *bytes.Buffer
+	}{{pcfMetrics, metrics}, {pcfBitmaps, bitmaps}, {pcfBDFEncodings, encodings}}
+	if pf.accelerators {
+		acc := table(format)
+		acc.Write(make([]byte, 8))
+		put(acc, int32(3), int32(1))
+		acc.Write(make([]byte, 40))
+		tables = append(tables, struct {
+			typ uint32
+			b   *bytes.Buffer
+		}{pcfBDFAccelerators, acc})
+	}
+	out := new(bytes.Buffer)
+	out.WriteString("\x01fcp")
+	binary.Write(out, binary.LittleEndian, uint32(len(tables)))
+	offset := 8 + 16*len(tables)
+	for _, t := range tables {
+		for t.b.Len()%4 != 0 {
+			t.b.WriteByte(0)
+		}
+		binary.Write(out, binary.LittleEndian, []uint32{t.typ, 0, uint32(t.b.Len()), uint32(offset)})
+		offset += t.b.Len()
+	}
+	for _, t := range tables {
+		out.Write(t.b.Bytes())
+	}
+	return out.Bytes()
+}
+
+func TestParsePCF(t *testing.T) {
+	var formats []pcfFormat
+	for _, msByte := range []bool{false, true} {
+		for _, msBit := range []bool{false, true} {
+			for _, pad := range []int{1, 2, 4} {
+				for _, unit := range []int{1, 2, 4} {
+					formats = append(formats,
+						pcfFormat{msByte: msByte, msBit: msBit, pad: pad, unit: unit, compressed: true, accelerators: true},
+						pcfFormat{msByte: msByte, msBit: msBit, pad: pad, unit: unit})
+				}
+			}
+		}
+	}
+	for _, pf := range formats {
+		t.Run(fmt.Sprintf("%+v", pf), func(t *testing.T) {
+			f, err := ParseFont(buildPCF(pf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkFont(t, f)
+		})
+	}
+}
+
+func TestParsePCFGzipped(t *testing.T) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(buildPCF(pcfFormat{msBit: true, pad: 4, unit: 1, compressed: true, accelerators: true}))
+	zw.Close()
+	f, err := ParseFont(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFont(t, f)
+}
+
+func TestParsePCFErrors(t *testing.T) {
+	good := buildPCF(pcfFormat{msByte: true, msBit: true, pad: 4, unit: 1})
+	// tableAt returns where the n'th table in the table of contents starts.
+	tableAt := func(n int) int {
+		return int(binary.LittleEndian.Uint32(good[8+16*n+12:]))
+	}
+	for _, tt := range []struct {
+		name string
+		edit func(b []byte) []byte
+		err  string
+	}{
+		{"truncated header", func(b []byte) []byte { return b[:6] }, "not a PCF font"},
+		{"truncated table of contents", func(b []byte) []byte { return b[:20] }, "unexpected EOF"},
+		{"table out of range", func(b []byte) []byte { return b[:len(b)-4] }, "out of range"},
+		{"missing table", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8+16*2:], pcfAccelerators)
+			return b
+		}, "missing table 0x20"},
+		{"metrics count", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[tableAt(0)+4:], 1<<30)
+			return b
+		}, "metrics: bad glyph count"},
+		{"bad glyph metrics", func(b []byte) []byte {
+			// right edge of the first glyph left of its left edge
+			binary.BigEndian.PutUint16(b[tableAt(0)+8+2:], 0xffff)
+			return b
+		}, "glyph 0: bad metrics"},
+		{"bitmap count", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[tableAt(1)+4:], 3)
+			return b
+		}, "bitmaps: 3 bitmaps for 4 glyphs"},
+		{"bitmap offset", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[tableAt(1)+8+4:], 1000)
+			return b
+		}, "glyph 1: bitmap out of range"},
+		{"bitmap size", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[tableAt(1)+8+4*len(testGlyphs)+8:], 1<<20)
+			return b
+		}, "bitmaps: unexpected EOF"},
+		{"encoding range", func(b []byte) []byte {
+			binary.BigEndian.PutUint16(b[tableAt(2)+4+6:], 0x7fff)
+			return b
+		}, "encodings: bad table"},
+	} {
+		_, err := ParsePCF(tt.edit(append([]byte(nil), good...)))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestBuiltinFonts(t *testing.T) {
+	for _, name := range fontNames() {
+		face, err := LoadFace(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		for _, r := range "0123456789 %.:" {
+			if _, ok := face.GlyphAdvance(r); !ok {
+				t.Errorf("%s: no glyph for %q", name, r)
+			}
+		}
+	}
+}
@@ -2,7 +2,23 @@ module lcdinator
 
 go 1.24.3
 
-require go.bug.st/serial v1.6.4
+require (
+	github.com/coreos/go-systemd/v22 v22.7.0
+	github.com/jsimonetti/rtnetlink v1.4.2
+	github.com/mdlayher/genetlink v1.3.2
+	github.com/mdlayher/netlink v1.7.2
+	go.bug.st/serial v1.6.4
+	golang.org/x/crypto v0.23.0
+)
+
+require (
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/josharian/native v1.1.0 // indirect
+	github.com/mdlayher/socket v0.4.1 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+)
 
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
@@ -14,5 +30,5 @@ require (
 require (
 	github.com/creack/goselect v0.1.2 // indirect
 	golang.org/x/image v0.27.0
-	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/sys v0.20.0
 )
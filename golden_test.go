@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"lcdinator/panel"
+	"lcdinator/panel/paneltest"
+)
+
+// fixedCollector holds made-up statistics, so that screens drawn from it
+// look the same on every machine.
+func fixedCollector() *Collector {
+	c := NewCollector(120)
+	c.snap = Snapshot{
+		CPU:   12.5,
+		Cores: []float64{5, 20, 50, 95},
+		Load:  [3]float64{0.52, 0.34, 0.2},
+		Temps: []Temperature{{Name: "cpu-thermal", Celsius: 48.3}, {Name: "nvme Composite", Celsius: 39}},
+		Mem: MemInfo{
+			Total: 4 << 30, Free: 1 << 30, Available: 3 << 30,
+			Buffers: 64 << 20, Cached: 1 << 30, Shmem: 12 << 20,
+			SwapTotal: 1 << 30, SwapFree: 900 << 20,
+		},
+		TopMem: []ProcessMem{
+			{PID: 812, Name: "postgres", RSS: 310 << 20},
+			{PID: 1203, Name: "lcdinator", RSS: 9 << 20},
+			{PID: 1, Name: "systemd", RSS: 8 << 20},
+			{PID: 455, Name: "sshd", RSS: 6 << 20},
+		},
+		Processes: []ProcessCPU{
+			{ProcStat: ProcStat{PID: 812, Name: "postgres"}, CPU: 37.5},
+			{ProcStat: ProcStat{PID: 1203, Name: "lcdinator"}, CPU: 2},
+			{ProcStat: ProcStat{PID: 455, Name: "sshd"}, CPU: 0.5},
+			{ProcStat: ProcStat{PID: 1, Name: "systemd"}, CPU: 0},
+		},
+		Disks: map[string]FSUsage{"/": {Total: 32 << 30, Used: 12 << 30, Avail: 18 << 30, Inodes: 2000000, InodesUsed: 300000}},
+		Mounts: []MountUsage{{
+			Mount:     Mount{Device: "/dev/mmcblk0p2", Path: "/", FSType: "ext4"},
+			FSUsage:   FSUsage{Total: 32 << 30, Used: 12 << 30, Avail: 18 << 30, Inodes: 2000000, InodesUsed: 300000},
+			ReadRate:  1536 << 10,
+			WriteRate: 250 << 10,
+		}},
+		Uptime: formatDuration(76*time.Hour + 12*time.Minute),
+		Interfaces: []NetInterfaceInfo{{
+			Name: "eth0", Addrs: []string{"192.168.1.20/24", "fe80::1/64"},
+			MAC: "02:00:00:00:00:01", MTU: 1500, Up: true, Carrier: true, Speed: 1000, Duplex: "full",
+			RxBytes: 5 << 30, TxBytes: 300 << 20, RxRate: 120 << 10, TxRate: 8 << 10,
+		}},
+	}
+	c.rx["eth0"], c.tx["eth0"] = NewRing(120), NewRing(120)
+	for i := 0; i < 120; i++ {
+		x := float64(i) / 10
+		c.cpu.Push(50 + 40*math.Sin(x))
+		c.mem.Push(25 + float64(i)/8)
+		c.rx["eth0"].Push(float64(i%30) * 4096)
+		c.tx["eth0"].Push(float64(i%7) * 1024)
+	}
+	return c
+}
+
+// fixedSystemd lists made-up services.
+func fixedSystemd() *Systemd {
+	s := NewSystemd()
+	s.err = nil
+	for _, u := range []Unit{
+		{Name: "cron.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Name: "nginx.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		{Name: "backup.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+		{Name: "sshd.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+	} {
+		s.units[u.Name] = u
+	}
+	return s
+}
+
+// TestScreenGoldens draws each screen from fixed data, sends it through
+// the real driver to the fake panel and compares what the panel shows with
+// testdata/<name>.png. Run with PANELTEST_UPDATE=1 to rewrite them.
+//
+// The service detail screen is left out: it loads from systemd over D-Bus
+// and from the journal in the background, so it has no fixed data to show.
+func TestScreenGoldens(t *testing.T) {
+	savedCollector, savedSystemd := collector, systemd
+	collector, systemd = fixedCollector(), fixedSystemd()
+	defer func() { collector, systemd = savedCollector, savedSystemd }()
+
+	nav := NewNavigator(nil)
+	cfg := DefaultConfig()
+	eth0 := IPv4Config{
+		Addrs:   []*net.IPNet{{IP: net.IPv4(192, 168, 1, 20), Mask: net.CIDRMask(24, 32)}},
+		Gateway: net.IPv4(192, 168, 1, 1),
+	}
+	// Overlays are drawn over the screen they would be pushed on.
+	over := func(under, overlay Screen) []Screen { return []Screen{under, overlay} }
+	goldens := []struct {
+		name    string
+		screens []Screen
+	}{
+		{"about", []Screen{&AboutScreen{}}},
+		{"system", []Screen{&SystemInfoScreen{DiskPath: "/"}}},
+		{"network", []Screen{&NetworkInfoScreen{nav: nav}}},
+		{"graph", []Screen{NewGraphScreen()}},
+		{"cpu", []Screen{NewCPUScreen()}},
+		{"disk", []Screen{NewDiskScreen()}},
+		{"memory", []Screen{NewMemoryScreen()}},
+		{"menu", []Screen{NewMenuScreen(nav, cfg)}},
+		{"services", []Screen{NewServiceManagerScreen(nav, cfg.Services)}},
+		{"processes", []Screen{NewProcessScreen(nav)}},
+		{"interface", []Screen{NewInterfaceScreen(nav, "eth0")}},
+		{"ipv4", []Screen{NewIPv4Screen(nav, "eth0", eth0, nil)}},
+		{"pin", []Screen{NewPINScreen(nav, panelLock, nil)}},
+		{"dialog", over(NewMenuScreen(nav, cfg), NewConfirmDialog(nav, "Reboot the system?", nil))},
+		{"keep-dialog", over(NewInterfaceScreen(nav, "eth0"), keepDialog(nav, "Keep eth0 down?", nil))},
+		{"value-dialog", over(NewProcessScreen(nav), NewValueDialog(nav, "Nice for postgres (812)", 0, -20, 19, nil))},
+	}
+
+	d, err := paneltest.New()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	defer d.Close()
+	lcd, err := panel.Open(d.Path(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lcd.Close()
+	lcd.SetInitDelay(0)
+	if err := lcd.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	display := NewDisplay(expectedImageWidth, expectedImageHeight)
+	for i, g := range goldens {
+		display.Clear()
+		for _, s := range g.screens {
+			s.Draw(display.Framebuffer)
+		}
+		if err := lcd.WriteFrame(display.Framebuffer); err != nil {
+			t.Fatal(err)
+		}
+		got, err := d.WaitFrames(i+1, time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		if err := paneltest.CheckGolden("testdata/"+g.name+".png", got); err != nil {
+			t.Errorf("%s: %v", g.name, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// GraphScreen graphs the last 120 samples of CPU and memory usage on its
+// first page and the bandwidth of one interface on each of the following
+// ones, however long that is at the sample interval. UP/DOWN change the
+// page.
+type GraphScreen struct {
+	Hide []string `json:"hide"` // interface names or glob patterns to leave out
+
+	screenFace
+	page int
+}
+
+func NewGraphScreen() *GraphScreen {
+	s := &GraphScreen{}
+	// The dense layout needs the small font to leave room for the graphs.
+	s.SetFace(defaultFace("5x7"))
+	return s
+}
+
+// interfaces returns the interfaces with history, without the hidden ones.
+func (s *GraphScreen) interfaces() []string {
+	var shown []string
+	for _, name := range collector.Interfaces() {
+		if !matchAny(s.Hide, name) {
+			shown = append(shown, name)
+		}
+	}
+	return shown
+}
+
+func (s *GraphScreen) Draw(fb *image.Gray) {
+	ifaces := s.interfaces()
+	if s.page > len(ifaces) {
+		s.page = 0
+	}
+	b := fb.Bounds()
+	top := image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+b.Dy()/2)
+	bottom := image.Rect(b.Min.X, top.Max.Y, b.Max.X, b.Max.Y)
+
+	if s.page == 0 {
+		cpu, mem := collector.CPU(), collector.Mem()
+		s.drawGraph(fb, top, fmt.Sprintf("CPU %3.0f%%", last(cpu)), cpu, 100, true)
+		s.drawGraph(fb, bottom, fmt.Sprintf("RAM %3.0f%%", last(mem)), mem, 100, true)
+		return
+	}
+	name := ifaces[s.page-1]
+	rx, tx := collector.Net(name)
+	// Both directions share a scale so they can be compared at a glance.
+	max := 1024.0
+	for _, v := range append(append([]float64(nil), rx...), tx...) {
+		if v > max {
+			max = v
+		}
+	}
+	s.drawGraph(fb, top, fmt.Sprintf("%s RX %s", name, formatRate(last(rx))), rx, max, false)
+	s.drawGraph(fb, bottom, fmt.Sprintf("TX %s", formatRate(last(tx))), tx, max, false)
+}
+
+// drawGraph draws a label line and, below it, a framed sparkline of values
+// filling the rest of r. With bar set the newest value, out of max, is
+// also shown as a bar next to the label.
+func (s *GraphScreen) drawGraph(fb *image.Gray, r image.Rectangle, label string, values []float64, max float64, bar bool) {
+	face := s.Face()
+	lineHeight := face.Metrics().Height.Ceil()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	d.Dot = fixed.P(r.Min.X, Baseline(face, r.Min.Y, lineHeight))
+	d.DrawString(label)
+	if bar {
+		x := d.Dot.X.Ceil() + 4
+		DrawProgressBar(fb, image.Rect(x, r.Min.Y+1, r.Max.X, r.Min.Y+lineHeight-1), last(values)/max)
+	}
+	graph := image.Rect(r.Min.X, r.Min.Y+lineHeight, r.Max.X, r.Max.Y-1)
+	DrawRect(fb, graph, color.Gray{Y: 0})
+	DrawSparkline(fb, graph.Inset(1), values, max)
+}
+
+func (s *GraphScreen) HandleKey(key byte) bool {
+	pages := len(s.interfaces()) + 1
+	switch key {
+	case KEY_UP:
+		s.page = (s.page + pages - 1) % pages
+		return true
+	case KEY_DOWN:
+		s.page = (s.page + 1) % pages
+		return true
+	}
+	return false
+}
+
+func last(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+// formatBytes formats a size compactly in binary units, e.g. "12.3K".
+func formatBytes(n uint64) string {
+	units := []string{"B", "K", "M", "G", "T"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", v, units[i])
+	}
+	if v >= 100 {
+		return fmt.Sprintf("%.0f%s", v, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", v, units[i])
+}
+
+// formatRate formats a rate in bytes per second compactly, e.g. "12.3K/s".
+func formatRate(bps float64) string {
+	return formatBytes(uint64(bps)) + "/s"
+}
@@ -91,4 +91,34 @@ var (
 		0b00011000,
 		0b00011000,
 	}
+	IconRunning = [8]byte{
+		0b01100000,
+		0b01111000,
+		0b01111110,
+		0b01111111,
+		0b01111110,
+		0b01111000,
+		0b01100000,
+		0b00000000,
+	}
+	IconStopped = [8]byte{
+		0b00000000,
+		0b01111110,
+		0b01000010,
+		0b01000010,
+		0b01000010,
+		0b01000010,
+		0b01111110,
+		0b00000000,
+	}
+	IconFailed = [8]byte{
+		0b11111111,
+		0b11000011,
+		0b10100101,
+		0b10011001,
+		0b10011001,
+		0b10100101,
+		0b11000011,
+		0b11111111,
+	}
 )
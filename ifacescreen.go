@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// rollbackTimeout is how long a network change that could cut the box off
+// the network waits to be confirmed before it is undone.
+const rollbackTimeout = 30 * time.Second
+
+// InterfaceScreen lists the actions for one network interface: bringing it
+// up or down, renewing its DHCP lease and setting a static IPv4 address.
+// Each is confirmed first, and taking the interface down or changing its
+// address is rolled back unless confirmed again in time. Changes are made
+// off the UI goroutine, one at a time, with what is going on shown in place
+// of the title.
+type InterfaceScreen struct {
+	nav    *Navigator
+	name   string
+	list   *ListView
+	status string // the change being made, if any
+}
+
+func NewInterfaceScreen(nav *Navigator, name string) *InterfaceScreen {
+	return &InterfaceScreen{
+		nav:  nav,
+		name: name,
+		list: NewListView(image.Rect(0, 16, 128, 64), 16),
+	}
+}
+
+func (s *InterfaceScreen) Draw(fb *image.Gray) {
+	s.refresh()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: basicfont.Face7x13}
+	d.Dot = fixed.P(0, Baseline(d.Face, 0, 16))
+	if s.status != "" {
+		d.DrawString(s.status)
+	} else {
+		d.DrawString(s.name + " config")
+	}
+	s.list.Draw(fb)
+}
+
+// refresh offers to bring the interface up or down depending on its state.
+func (s *InterfaceScreen) refresh() {
+	link := "Bring up"
+	for _, iface := range collector.Snapshot().Interfaces {
+		if iface.Name == s.name && iface.Up {
+			link = "Bring down"
+		}
+	}
+	s.list.SetItems([]ListItem{{Label: link}, {Label: "Renew DHCP"}, {Label: "Static IPv4"}})
+}
+
+func (s *InterfaceScreen) Animating() bool {
+	return s.list.Animating()
+}
+
+func (s *InterfaceScreen) HandleKey(key byte) bool {
+	if s.list.HandleKey(key) {
+		return true
+	}
+	if key != KEY_ENTER {
+		return false
+	}
+	if s.status != "" {
+		// Wait for the last change to be made.
+		return true
+	}
+	item, _ := s.list.SelectedItem()
+	switch item.Label {
+	case "Bring up":
+		s.nav.Push(NewConfirmDialog(s.nav, fmt.Sprintf("Bring %s up?", s.name), panelLock.Guard(s.nav, func() {
+			s.background("Bringing up...", func() func() {
+				if err := SetLinkUp(s.name, true); err != nil {
+					log.Printf("Bring %s up: %v", s.name, err)
+				}
+				return nil
+			})
+		})))
+	case "Bring down":
+		s.nav.Push(NewConfirmDialog(s.nav, fmt.Sprintf("Bring %s down?", s.name), panelLock.Guard(s.nav, s.linkDown)))
+	case "Renew DHCP":
+		s.nav.Push(NewConfirmDialog(s.nav, fmt.Sprintf("Renew DHCP on %s?", s.name), panelLock.Guard(s.nav, func() {
+			s.background("Renewing DHCP...", func() func() {
+				if err := RenewDHCP(s.name); err != nil {
+					log.Printf("Renew DHCP on %s: %v", s.name, err)
+				}
+				return nil
+			})
+		})))
+	case "Static IPv4":
+		s.background("Reading...", func() func() {
+			prev, err := GetIPv4Config(s.name)
+			if err != nil {
+				log.Printf("Read IPv4 config of %s: %v", s.name, err)
+				return nil
+			}
+			return func() {
+				s.nav.Push(NewIPv4Screen(s.nav, s.name, prev, func(cfg IPv4Config) {
+					panelLock.Guard(s.nav, func() { s.setIPv4(cfg, prev) })()
+				}))
+			}
+		})
+	}
+	return true
+}
+
+// background runs fn off the UI goroutine, since netlink calls and pings
+// take a while, showing status until it is done. The function fn returns,
+// if any, then runs on the UI goroutine.
+func (s *InterfaceScreen) background(status string, fn func() (then func())) {
+	s.status = status
+	go func() {
+		then := fn()
+		s.nav.Do(func() {
+			s.status = ""
+			if then != nil {
+				then()
+			}
+		})
+	}()
+}
+
+// linkDown takes the interface down until the change is confirmed. The
+// kernel drops the interface's routes when it goes down, so rolling back
+// puts its addresses and default route back as well.
+func (s *InterfaceScreen) linkDown() {
+	s.background("Bringing down...", func() func() {
+		prev, err := GetIPv4Config(s.name)
+		if err != nil {
+			log.Printf("Read IPv4 config of %s: %v", s.name, err)
+			return nil
+		}
+		if err := SetLinkUp(s.name, false); err != nil {
+			log.Printf("Bring %s down: %v", s.name, err)
+			return nil
+		}
+		return func() {
+			s.nav.Push(keepDialog(s.nav, fmt.Sprintf("Keep %s down?", s.name), func() {
+				s.background("Rolling back...", func() func() {
+					if err := SetLinkUp(s.name, true); err != nil {
+						log.Printf("Roll back %s: %v", s.name, err)
+						return nil
+					}
+					if err := SetIPv4Config(s.name, prev); err != nil {
+						log.Printf("Roll back %s: %v", s.name, err)
+					}
+					return nil
+				})
+			}))
+		}
+	})
+}
+
+// setIPv4 applies cfg until the change is confirmed, going back to prev
+// if it isn't or if the new gateway doesn't answer. The gateway is tried
+// before the change is offered for keeping, so it can't be kept early.
+func (s *InterfaceScreen) setIPv4(cfg, prev IPv4Config) {
+	rollback := func() func() {
+		if err := SetIPv4Config(s.name, prev); err != nil {
+			log.Printf("Roll back %s: %v", s.name, err)
+		}
+		return nil
+	}
+	s.background("Applying...", func() func() {
+		if err := SetIPv4Config(s.name, cfg); err != nil {
+			log.Printf("Set IPv4 on %s: %v", s.name, err)
+			return rollback()
+		}
+		if cfg.Gateway != nil && !GatewayReachable(cfg.Gateway) {
+			log.Printf("Gateway %s unreachable from %s, rolling back", cfg.Gateway, s.name)
+			return rollback()
+		}
+		return func() {
+			s.nav.Push(keepDialog(s.nav, fmt.Sprintf("Keep %s settings?", s.name), func() {
+				s.background("Rolling back...", rollback)
+			}))
+		}
+	})
+}
+
+// keepDialog asks to keep a change, running rollback if the answer is no
+// or doesn't come within rollbackTimeout.
+func keepDialog(nav *Navigator, message string, rollback func()) *Dialog {
+	d := NewTimedDialog(nav, message, rollbackTimeout, nil, rollback)
+	d.OKLabel, d.CancelLabel = "Keep", "Undo"
+	return d
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"net"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ipv4Fields are the numbers IPv4Screen edits, with their digits and
+// largest value: the address octets, the prefix length and the gateway
+// octets. A gateway of 0.0.0.0 means none.
+var ipv4Fields = [9]struct{ digits, max int }{
+	{3, 255}, {3, 255}, {3, 255}, {3, 255},
+	{2, 32},
+	{3, 255}, {3, 255}, {3, 255}, {3, 255},
+}
+
+// IPv4Screen edits a static IPv4 address, prefix length and gateway one
+// digit at a time: LEFT/RIGHT move the cursor, UP/DOWN change the digit
+// under it and ENTER applies the result after a confirmation.
+type IPv4Screen struct {
+	nav     *Navigator
+	name    string
+	values  [9]int // see ipv4Fields
+	cursor  int    // digit, counted over all fields
+	message string // why the last ENTER was refused
+	onApply func(IPv4Config)
+}
+
+// NewIPv4Screen starts from the first address and the gateway in cur.
+func NewIPv4Screen(nav *Navigator, name string, cur IPv4Config, onApply func(IPv4Config)) *IPv4Screen {
+	s := &IPv4Screen{nav: nav, name: name, onApply: onApply}
+	s.values[4] = 24
+	if len(cur.Addrs) > 0 {
+		if ip := cur.Addrs[0].IP.To4(); ip != nil {
+			for i, b := range ip {
+				s.values[i] = int(b)
+			}
+			s.values[4], _ = cur.Addrs[0].Mask.Size()
+		}
+	}
+	if gw := cur.Gateway.To4(); gw != nil {
+		for i, b := range gw {
+			s.values[5+i] = int(b)
+		}
+	}
+	return s
+}
+
+func (s *IPv4Screen) Draw(fb *image.Gray) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	v := s.values
+	rows := []string{
+		s.name + " static IPv4",
+		fmt.Sprintf("%03d.%03d.%03d.%03d/%02d", v[0], v[1], v[2], v[3], v[4]),
+		fmt.Sprintf("GW %03d.%03d.%03d.%03d", v[5], v[6], v[7], v[8]),
+		s.message,
+	}
+	if s.message == "" {
+		rows[3] = "Enter: apply"
+	}
+	digit := 0
+	for i, row := range rows {
+		baseline := Baseline(face, 16*i, 16)
+		d.Dot = fixed.P(0, baseline)
+		for _, c := range row {
+			// Only the address and gateway rows have digits to edit.
+			if i == 1 || i == 2 {
+				if c >= '0' && c <= '9' {
+					if digit == s.cursor {
+						x := d.Dot.X.Ceil()
+						w := d.MeasureString(string(c)).Ceil()
+						FillRect(fb, image.Rect(x, 16*i+1, x+w, 16*(i+1)-1), color.Gray{Y: 0})
+						d.Src = image.White
+					}
+					digit++
+				}
+			}
+			d.DrawString(string(c))
+			d.Src = image.Black
+		}
+	}
+}
+
+// digit returns the field the cursor is in and the power of ten of the
+// digit under it.
+func (s *IPv4Screen) digit() (field, place int) {
+	n := s.cursor
+	for field, f := range ipv4Fields {
+		if n < f.digits {
+			place = 1
+			for i := 0; i < f.digits-1-n; i++ {
+				place *= 10
+			}
+			return field, place
+		}
+		n -= f.digits
+	}
+	return 0, 1
+}
+
+func (s *IPv4Screen) numDigits() int {
+	n := 0
+	for _, f := range ipv4Fields {
+		n += f.digits
+	}
+	return n
+}
+
+func (s *IPv4Screen) HandleKey(key byte) bool {
+	switch key {
+	case KEY_LEFT:
+		s.cursor = (s.cursor + s.numDigits() - 1) % s.numDigits()
+	case KEY_RIGHT:
+		s.cursor = (s.cursor + 1) % s.numDigits()
+	case KEY_UP, KEY_DOWN:
+		field, place := s.digit()
+		v := s.values[field]
+		d := v / place % 10
+		nd := (d + 1) % 10
+		if key == KEY_DOWN {
+			nd = (d + 9) % 10
+		}
+		v += (nd - d) * place
+		s.values[field] = min(v, ipv4Fields[field].max)
+	case KEY_ENTER:
+		cfg, err := s.config()
+		if err != nil {
+			s.message = err.Error()
+			return true
+		}
+		s.message = ""
+		msg := fmt.Sprintf("Set %s to %s?", s.name, cfg.Addrs[0])
+		s.nav.Push(NewConfirmDialog(s.nav, msg, func() {
+			s.nav.Remove(s)
+			s.onApply(cfg)
+		}))
+	default:
+		return false
+	}
+	return true
+}
+
+// config checks what has been entered and converts it.
+func (s *IPv4Screen) config() (IPv4Config, error) {
+	v := s.values
+	ip := net.IPv4(byte(v[0]), byte(v[1]), byte(v[2]), byte(v[3])).To4()
+	addr := &net.IPNet{IP: ip, Mask: net.CIDRMask(v[4], 32)}
+	cfg := IPv4Config{Addrs: []*net.IPNet{addr}}
+	switch {
+	case ip.IsUnspecified():
+		return cfg, fmt.Errorf("No address")
+	case v[4] == 0:
+		return cfg, fmt.Errorf("No prefix")
+	case v[4] < 31 && (ip.Equal(ip.Mask(addr.Mask)) || ip.Equal(broadcastOf(addr))):
+		// The network and broadcast addresses can't be used for a host,
+		// except on point-to-point /31 and /32 links.
+		return cfg, fmt.Errorf("Bad address")
+	}
+	gw := net.IPv4(byte(v[5]), byte(v[6]), byte(v[7]), byte(v[8])).To4()
+	if !gw.IsUnspecified() {
+		if !addr.Contains(gw) || gw.Equal(ip) {
+			return cfg, fmt.Errorf("Bad gateway")
+		}
+		cfg.Gateway = gw
+	}
+	return cfg, nil
+}
+
+// broadcastOf returns the last address of n.
+func broadcastOf(n *net.IPNet) net.IP {
+	ip := make(net.IP, len(n.IP))
+	for i := range ip {
+		ip[i] = n.IP[i] | ^n.Mask[i]
+	}
+	return ip
+}
@@ -34,25 +34,3 @@ func (d *Display) Clear() {
 func (d *Display) DrawDrawable(dr Drawable) {
 	dr.Draw(d.Framebuffer)
 }
-
-func (d *Display) Pack() []byte {
-	bytesPerScanline := d.Width / 8
-	framebuffer := make([]byte, bytesPerScanline*d.Height)
-	for y := 0; y < d.Height; y++ {
-		flippedY := d.Height - 1 - y // vertical flip
-		for xByte := 0; xByte < bytesPerScanline; xByte++ {
-			var b byte
-			for bit := 0; bit < 8; bit++ {
-				x := xByte*8 + bit
-				if x >= d.Width {
-					continue
-				}
-				if d.Framebuffer.GrayAt(x, flippedY).Y < 128 {
-					b |= 1 << (7 - bit)
-				}
-			}
-			framebuffer[y*bytesPerScanline+xByte] = b
-		}
-	}
-	return framebuffer
-}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ListItem is one row of a ListView.
+type ListItem struct {
+	Label string
+	Key   string   // identifies the item across SetItems; the label if empty
+	Icon  *[8]byte // optional, drawn before the label
+}
+
+func (item ListItem) key() string {
+	if item.Key != "" {
+		return item.Key
+	}
+	return item.Label
+}
+
+// ListView is a scrollable list with a selection cursor and a scrollbar.
+// A screen owns one, refreshes its items with SetItems, forwards keys to
+// HandleKey and calls Draw.
+type ListView struct {
+	Rect      image.Rectangle // area the rows and scrollbar are drawn in
+	RowHeight int
+	Face      font.Face // basicfont.Face7x13 if nil
+
+	items     []ListItem
+	selected  int
+	offset    int // first visible row
+	marquee   Marquee
+	animating bool
+}
+
+// NewListView returns a list drawn in rect with rows of rowHeight pixels.
+func NewListView(rect image.Rectangle, rowHeight int) *ListView {
+	return &ListView{Rect: rect, RowHeight: rowHeight}
+}
+
+// SetItems replaces the items. If the selected item is still in the list,
+// by key, it stays selected even if it moved.
+func (l *ListView) SetItems(items []ListItem) {
+	if l.selected < len(l.items) {
+		prev := l.items[l.selected].key()
+		for i, item := range items {
+			if item.key() == prev {
+				l.selected = i
+				break
+			}
+		}
+	}
+	l.items = items
+	l.clamp()
+}
+
+func (l *ListView) Len() int {
+	return len(l.items)
+}
+
+// Selected returns the index of the selected item, or -1 if the list is
+// empty.
+func (l *ListView) Selected() int {
+	if len(l.items) == 0 {
+		return -1
+	}
+	return l.selected
+}
+
+// SelectedItem returns the selected item; ok is false if the list is empty.
+func (l *ListView) SelectedItem() (item ListItem, ok bool) {
+	if len(l.items) == 0 {
+		return ListItem{}, false
+	}
+	return l.items[l.selected], true
+}
+
+// Select moves the cursor to index i.
+func (l *ListView) Select(i int) {
+	if i != l.selected {
+		l.marquee.Reset()
+	}
+	l.selected = i
+	l.clamp()
+}
+
+// Animating reports whether the last Draw left the selected label
+// scrolling.
+func (l *ListView) Animating() bool {
+	return l.animating
+}
+
+// HandleKey moves the selection with UP/DOWN and reports whether it moved.
+func (l *ListView) HandleKey(key byte) bool {
+	switch key {
+	case KEY_UP:
+		if l.selected > 0 {
+			l.Select(l.selected - 1)
+			return true
+		}
+	case KEY_DOWN:
+		if l.selected < len(l.items)-1 {
+			l.Select(l.selected + 1)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *ListView) visibleRows() int {
+	if l.RowHeight <= 0 {
+		return 0
+	}
+	return l.Rect.Dy() / l.RowHeight
+}
+
+// clamp keeps the selection in range and scrolled into view.
+func (l *ListView) clamp() {
+	n := len(l.items)
+	if l.selected >= n {
+		l.selected = n - 1
+	}
+	if l.selected < 0 {
+		l.selected = 0
+	}
+	rows := l.visibleRows()
+	if n <= rows {
+		l.offset = 0
+		return
+	}
+	if l.selected < l.offset {
+		l.offset = l.selected
+	} else if l.selected >= l.offset+rows {
+		l.offset = l.selected - rows + 1
+	}
+	if l.offset < 0 {
+		l.offset = 0
+	}
+	if l.offset > n-rows {
+		l.offset = n - rows
+	}
+}
+
+func (l *ListView) face() font.Face {
+	if l.Face != nil {
+		return l.Face
+	}
+	return basicfont.Face7x13
+}
+
+func (l *ListView) Draw(fb *image.Gray) {
+	face := l.face()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	prefixWidth := d.MeasureString("> ").Ceil()
+
+	l.animating = false
+	rows := l.visibleRows()
+	textRight := l.Rect.Max.X
+	if len(l.items) > rows {
+		textRight -= 4 // room for the scrollbar
+	}
+
+	for i := 0; i < rows; i++ {
+		idx := l.offset + i
+		if idx >= len(l.items) {
+			break
+		}
+		item := l.items[idx]
+		top := l.Rect.Min.Y + i*l.RowHeight
+		baseline := Baseline(face, top, l.RowHeight)
+
+		x := l.Rect.Min.X
+		if idx == l.selected {
+			d.Dot = fixed.P(x, baseline)
+			d.DrawString(">")
+		}
+		x += prefixWidth
+		if item.Icon != nil {
+			DrawIcon(fb, x, baseline-8, *item.Icon)
+			x += 10
+		}
+		// Clip labels so long names don't run into the scrollbar; the
+		// selected one scrolls to show the rest.
+		clip := image.Rect(x, top, textRight, top+l.RowHeight)
+		if idx == l.selected {
+			l.animating = l.marquee.Draw(d, clip, baseline, item.Label)
+			continue
+		}
+		d.Dst = fb.SubImage(clip).(*image.Gray)
+		d.Dot = fixed.P(x, baseline)
+		d.DrawString(item.Label)
+		d.Dst = fb
+	}
+
+	if len(l.items) > rows {
+		l.drawScrollbar(fb, rows)
+	}
+}
+
+func (l *ListView) drawScrollbar(fb *image.Gray, rows int) {
+	n := len(l.items)
+	areaHeight := rows * l.RowHeight
+	x := l.Rect.Max.X - 3 // 2px wide scrollbar
+
+	thumbHeight := areaHeight * rows / n
+	if thumbHeight < 3 { // Min thumb height
+		thumbHeight = 3
+	}
+	if thumbHeight > areaHeight {
+		thumbHeight = areaHeight
+	}
+	thumbTop := l.Rect.Min.Y + l.offset*(areaHeight-thumbHeight)/(n-rows)
+	for y := thumbTop; y < thumbTop+thumbHeight && y < l.Rect.Min.Y+areaHeight; y++ {
+		fb.Set(x, y, image.Black)
+		fb.Set(x+1, y, image.Black)
+	}
+}
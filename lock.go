@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPINLength is the longest PIN the PIN screen takes.
+const maxPINLength = 8
+
+// Lock keeps destructive actions behind a PIN when one is configured. Once
+// the PIN has been entered, actions run without asking again until no key
+// has been pressed for the relock time. Too many wrong PINs in a row lock
+// the PIN out for a while. All methods are safe to call from any goroutine.
+type Lock struct {
+	mu       sync.Mutex
+	hash     []byte // bcrypt; nil if there is no PIN
+	attempts int
+	lockout  time.Duration
+	relock   time.Duration
+
+	unlocked    bool
+	lastKey     time.Time
+	failures    int
+	lockedUntil time.Time
+}
+
+// panelLock is configured from main and guards the actions of the menu,
+// service, process and interface screens.
+var panelLock = &Lock{}
+
+// Configure sets the PIN and limits, and locks the panel if there is a PIN.
+func (l *Lock) Configure(cfg LockConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hash = nil
+	if cfg.PIN != "" {
+		l.hash = []byte(cfg.PIN)
+	}
+	l.attempts = cfg.Attempts
+	l.lockout = time.Duration(cfg.Lockout)
+	l.relock = time.Duration(cfg.Relock)
+	l.unlocked = false
+	l.failures = 0
+}
+
+// Touch records a key press, locking the panel again first if it has been
+// idle for longer than the relock time.
+func (l *Lock) Touch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.unlocked && time.Since(l.lastKey) > l.relock {
+		l.unlocked = false
+	}
+	l.lastKey = time.Now()
+}
+
+// Locked reports whether actions need the PIN.
+func (l *Lock) Locked() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.hash != nil && (!l.unlocked || time.Since(l.lastKey) > l.relock)
+}
+
+// Guard returns fn wrapped to ask for the PIN first while the panel is
+// locked. Without the right PIN fn doesn't run.
+func (l *Lock) Guard(nav *Navigator, fn func()) func() {
+	return func() {
+		if !l.Locked() {
+			fn()
+			return
+		}
+		nav.Push(NewPINScreen(nav, l, fn))
+	}
+}
+
+// Try checks pin and unlocks the panel if it is right. It returns an error
+// saying why not otherwise. Checking takes a while on purpose, so call it
+// off the UI goroutine.
+func (l *Lock) Try(pin string) error {
+	l.mu.Lock()
+	hash, wait := l.hash, time.Until(l.lockedUntil)
+	l.mu.Unlock()
+	if wait > 0 {
+		return fmt.Errorf("Locked for %s", wait.Round(time.Second))
+	}
+	err := bcrypt.CompareHashAndPassword(hash, []byte(pin))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		l.unlocked = true
+		l.lastKey = time.Now()
+		l.failures = 0
+		return nil
+	}
+	l.failures++
+	if l.failures >= l.attempts {
+		l.failures = 0
+		l.lockedUntil = time.Now().Add(l.lockout)
+		return fmt.Errorf("Wrong PIN, locked for %s", l.lockout)
+	}
+	return fmt.Errorf("Wrong PIN, %d left", l.attempts-l.failures)
+}
+
+// HashPIN reads a PIN from r and returns its hash for lock.pin.
+func HashPIN(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	pin := strings.TrimSpace(line)
+	if len(pin) == 0 || len(pin) > maxPINLength || strings.Trim(pin, "0123456789") != "" {
+		return "", fmt.Errorf("a PIN is 1 to %d digits", maxPINLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	return string(hash), err
+}
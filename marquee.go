@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	marqueePause = time.Second // how long the start and end of the text stay put
+	marqueeSpeed = 25          // pixels per second
+)
+
+// Marquee draws a line of text clipped to a box. Text that doesn't fit
+// scrolls left until its end is visible, pauses, then starts over. A new
+// text restarts the animation from the beginning.
+type Marquee struct {
+	text  string
+	start time.Time
+}
+
+// Draw draws text inside clip with its baseline at y and reports whether
+// the text is scrolling, i.e. whether it should be redrawn soon.
+func (m *Marquee) Draw(d *font.Drawer, clip image.Rectangle, y int, text string) bool {
+	now := time.Now()
+	if text != m.text || m.start.IsZero() {
+		m.text = text
+		m.start = now
+	}
+
+	offset := 0
+	overflow := d.MeasureString(text).Ceil() - clip.Dx()
+	if overflow > 0 {
+		scroll := time.Duration(overflow) * time.Second / marqueeSpeed
+		t := now.Sub(m.start) % (2*marqueePause + scroll)
+		switch {
+		case t < marqueePause:
+			offset = 0
+		case t < marqueePause+scroll:
+			offset = int((t - marqueePause) * marqueeSpeed / time.Second)
+		default:
+			offset = overflow
+		}
+	}
+
+	dst := d.Dst
+	if sub, ok := dst.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		d.Dst = sub.SubImage(clip).(draw.Image)
+	}
+	d.Dot = fixed.P(clip.Min.X-offset, y)
+	d.DrawString(text)
+	d.Dst = dst
+	return overflow > 0
+}
+
+// Reset restarts the animation on the next Draw.
+func (m *Marquee) Reset() {
+	m.start = time.Time{}
+}
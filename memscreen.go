@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// MemoryScreen shows memory and swap usage above a list of the processes
+// with the largest resident set, scrolled with UP/DOWN.
+type MemoryScreen struct {
+	screenFace
+	list *ListView
+}
+
+func NewMemoryScreen() *MemoryScreen {
+	s := &MemoryScreen{}
+	s.SetFace(defaultFace("5x7"))
+	return s
+}
+
+// layout returns the height of a summary row and where the list goes,
+// which depend on the font.
+func (s *MemoryScreen) layout(b image.Rectangle) (rowHeight int, list image.Rectangle) {
+	rowHeight = s.Face().Metrics().Height.Ceil() + 1
+	return rowHeight, image.Rect(b.Min.X, b.Min.Y+3*rowHeight+1, b.Max.X, b.Max.Y)
+}
+
+func (s *MemoryScreen) Draw(fb *image.Gray) {
+	face := s.Face()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	b := fb.Bounds()
+	snap := collector.Snapshot()
+	mem := snap.Mem
+	rowHeight, listRect := s.layout(b)
+	row := func(i int) image.Rectangle {
+		top := b.Min.Y + i*rowHeight
+		return image.Rect(b.Min.X, top, b.Max.X, top+rowHeight)
+	}
+
+	labelWidth := d.MeasureString("Swap ").Ceil()
+	frac := -1.0
+	if mem.Total > 0 {
+		frac = float64(mem.Used()) / float64(mem.Total)
+	}
+	DrawGauge(fb, d, row(0), "RAM", labelWidth, frac)
+	d.Dot = fixed.P(b.Min.X, Baseline(face, row(1).Min.Y, rowHeight))
+	d.DrawString(fmt.Sprintf("Avail %s Shm %s", formatBytes(mem.Available), formatBytes(mem.Shmem)))
+	if mem.SwapTotal > 0 {
+		DrawGauge(fb, d, row(2), "Swap", labelWidth, float64(mem.SwapUsed())/float64(mem.SwapTotal))
+	} else {
+		d.Dot = fixed.P(b.Min.X, Baseline(face, row(2).Min.Y, rowHeight))
+		d.DrawString("Swap none")
+	}
+
+	if s.list == nil {
+		s.list = NewListView(listRect, rowHeight)
+	}
+	s.list.Rect, s.list.RowHeight, s.list.Face = listRect, rowHeight, face
+	s.refresh(snap.TopMem)
+	s.list.Draw(fb)
+}
+
+// refresh fills the list with the top processes, keeping the cursor on the
+// same process while the order changes.
+func (s *MemoryScreen) refresh(procs []ProcessMem) {
+	items := make([]ListItem, len(procs))
+	for i, p := range procs {
+		items[i] = ListItem{Label: fmt.Sprintf("%6s %s", formatBytes(p.RSS), p.Name), Key: strconv.Itoa(p.PID)}
+	}
+	s.list.SetItems(items)
+}
+
+func (s *MemoryScreen) Needs() Need {
+	return NeedTopMem
+}
+
+func (s *MemoryScreen) Animating() bool {
+	return s.list != nil && s.list.Animating()
+}
+
+func (s *MemoryScreen) HandleKey(key byte) bool {
+	if s.list == nil {
+		return false
+	}
+	return s.list.HandleKey(key)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"image"
+	"sync"
+)
+
+// Navigator decides which screen is shown and where key presses go.
+//
+// The rotation screens are cycled with LEFT/RIGHT. Anything else - the menu,
+// the About screen, sub-screens and dialogs - is pushed on a stack on top of
+// the current rotation screen. The top of the stack gets every key first;
+// an ESC it doesn't handle pops it. At the bottom of the stack HELP pushes
+// About and ESC pushes the menu.
+type Navigator struct {
+	// ui serializes Draw, HandleKey and Do so screens can keep plain fields
+	// for their state.
+	ui sync.Mutex
+
+	mu       sync.Mutex
+	rotation []Screen
+	current  int
+	stack    []Screen
+	about    Screen
+	menu     Screen
+}
+
+func NewNavigator(rotation []Screen) *Navigator {
+	return &Navigator{rotation: rotation, about: &AboutScreen{}}
+}
+
+// SetMenu sets the screen ESC opens from the rotation.
+func (n *Navigator) SetMenu(menu Screen) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.menu = menu
+}
+
+// Push shows s on top of the current screen.
+func (n *Navigator) Push(s Screen) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stack = append(n.stack, s)
+}
+
+// Pop closes the topmost pushed screen. It does nothing at the bottom of
+// the stack.
+func (n *Navigator) Pop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.stack) > 0 {
+		n.stack[len(n.stack)-1] = nil
+		n.stack = n.stack[:len(n.stack)-1]
+	}
+}
+
+// Remove closes s wherever it is in the stack, for screens that close by
+// themselves while something else may be on top of them.
+func (n *Navigator) Remove(s Screen) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i := len(n.stack) - 1; i >= 0; i-- {
+		if n.stack[i] == s {
+			n.stack = append(n.stack[:i], n.stack[i+1:]...)
+			return
+		}
+	}
+}
+
+// Home closes every pushed screen and goes back to the first rotation
+// screen.
+func (n *Navigator) Home() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stack = nil
+	n.current = 0
+}
+
+// Current returns the screen being shown.
+func (n *Navigator) Current() Screen {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.stack) > 0 {
+		return n.stack[len(n.stack)-1]
+	}
+	return n.rotation[n.current]
+}
+
+// Do runs fn serialized with Draw and HandleKey, for code outside the key
+// handler (e.g. a finished background job) that needs to touch screen state.
+func (n *Navigator) Do(fn func()) {
+	n.ui.Lock()
+	defer n.ui.Unlock()
+	fn()
+}
+
+// overlay is implemented by pushed screens, like Dialog, that are drawn on
+// top of the screen below them rather than replacing it.
+type overlay interface {
+	Screen
+	isOverlay()
+}
+
+// Animator is implemented by screens with moving content, like marquee
+// text. While the screen shown reports true, the main loop redraws on the
+// faster animation tick.
+type Animator interface {
+	Animating() bool
+}
+
+// Animating reports whether any visible screen is animating.
+func (n *Navigator) Animating() bool {
+	n.ui.Lock()
+	defer n.ui.Unlock()
+	for _, s := range n.visible() {
+		if a, ok := s.(Animator); ok && a.Animating() {
+			return true
+		}
+	}
+	return false
+}
+
+// Needs returns the costly samples the visible screens show.
+func (n *Navigator) Needs() Need {
+	n.ui.Lock()
+	defer n.ui.Unlock()
+	var needs Need
+	for _, s := range n.visible() {
+		if nd, ok := s.(Needer); ok {
+			needs |= nd.Needs()
+		}
+	}
+	return needs
+}
+
+// visible returns the screens Draw draws, bottom first: the topmost screen
+// that isn't an overlay and every overlay above it.
+func (n *Navigator) visible() []Screen {
+	n.mu.Lock()
+	screens := append([]Screen{n.rotation[n.current]}, n.stack...)
+	n.mu.Unlock()
+
+	base := len(screens) - 1
+	for base > 0 {
+		if _, ok := screens[base].(overlay); !ok {
+			break
+		}
+		base--
+	}
+	return screens[base:]
+}
+
+func (n *Navigator) Draw(fb *image.Gray) {
+	n.ui.Lock()
+	defer n.ui.Unlock()
+	for _, s := range n.visible() {
+		s.Draw(fb)
+	}
+}
+
+// HandleKey routes a key press and reports whether the display needs to be
+// redrawn.
+func (n *Navigator) HandleKey(key byte) bool {
+	n.ui.Lock()
+	defer n.ui.Unlock()
+
+	n.mu.Lock()
+	depth := len(n.stack)
+	n.mu.Unlock()
+
+	if depth > 0 {
+		if n.Current().HandleKey(key) {
+			return true
+		}
+		if key == KEY_ESC {
+			n.Pop()
+			return true
+		}
+		return false
+	}
+
+	switch key {
+	case KEY_LEFT:
+		n.cycle(-1)
+		return true
+	case KEY_RIGHT:
+		n.cycle(1)
+		return true
+	case KEY_HELP:
+		n.Push(n.about)
+		return true
+	case KEY_ESC:
+		n.mu.Lock()
+		menu := n.menu
+		n.mu.Unlock()
+		if menu == nil {
+			return false
+		}
+		n.Push(menu)
+		return true
+	}
+	return n.Current().HandleKey(key)
+}
+
+func (n *Navigator) cycle(delta int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.current = (n.current + delta + len(n.rotation)) % len(n.rotation)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+
+	"github.com/jsimonetti/rtnetlink/rtnl"
+	"golang.org/x/sys/unix"
+)
+
+// IPv4Config is the IPv4 setup of an interface that can be changed from
+// the front panel: its addresses and the default route through it.
+type IPv4Config struct {
+	Addrs   []*net.IPNet // host address and prefix, e.g. 192.168.1.20/24
+	Gateway net.IP       // nil for no default route
+}
+
+// SetLinkUp brings an interface up or down.
+func SetLinkUp(name string, up bool) error {
+	ifc, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	conn, err := rtnl.Dial(nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if up {
+		return conn.LinkUp(ifc)
+	}
+	return conn.LinkDown(ifc)
+}
+
+// GetIPv4Config reads the IPv4 addresses of an interface and the gateway
+// of the default route through it, if any.
+func GetIPv4Config(name string) (IPv4Config, error) {
+	var cfg IPv4Config
+	ifc, err := net.InterfaceByName(name)
+	if err != nil {
+		return cfg, err
+	}
+	conn, err := rtnl.Dial(nil)
+	if err != nil {
+		return cfg, err
+	}
+	defer conn.Close()
+	if cfg.Addrs, err = conn.Addrs(ifc, unix.AF_INET); err != nil {
+		return cfg, err
+	}
+	routes, err := conn.Conn.Route.List()
+	if err != nil {
+		return cfg, err
+	}
+	for _, r := range routes {
+		if r.Family == unix.AF_INET && r.Table == unix.RT_TABLE_MAIN && r.DstLength == 0 &&
+			r.Attributes.OutIface == uint32(ifc.Index) && r.Attributes.Gateway != nil {
+			cfg.Gateway = r.Attributes.Gateway
+			break
+		}
+	}
+	return cfg, nil
+}
+
+// SetIPv4Config replaces the IPv4 addresses of an interface and the
+// default route through it with cfg. This only changes the running
+// system: a DHCP client or network manager may later put back its own.
+func SetIPv4Config(name string, cfg IPv4Config) error {
+	ifc, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	conn, err := rtnl.Dial(nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	old, err := conn.Addrs(ifc, unix.AF_INET)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	dst := net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	// There may be no default route through the interface to delete.
+	conn.RouteDel(ifc, dst)
+	for _, addr := range old {
+		if err := conn.AddrDel(ifc, addr); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", addr, err))
+		}
+	}
+	for _, addr := range cfg.Addrs {
+		if err := conn.AddrAdd(ifc, addr); err != nil {
+			errs = append(errs, fmt.Errorf("add %s: %w", addr, err))
+		}
+	}
+	if cfg.Gateway != nil {
+		if err := conn.RouteReplace(ifc, dst, cfg.Gateway.To4()); err != nil {
+			errs = append(errs, fmt.Errorf("default route via %s: %w", cfg.Gateway, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dhcpClients are tried in order by RenewDHCP. The first one that is
+// installed and succeeds wins, so systems with a network manager renew
+// through it and the others fall back to dhclient.
+var dhcpClients = [][]string{
+	{"networkctl", "renew"},
+	{"nmcli", "device", "up"},
+	{"dhclient", "-1"},
+}
+
+// RenewDHCP asks whatever manages the interface to renew its DHCP lease.
+func RenewDHCP(name string) error {
+	err := errors.New("no DHCP client found")
+	for _, client := range dhcpClients {
+		if _, lookErr := exec.LookPath(client[0]); lookErr != nil {
+			continue
+		}
+		args := append(client[1:len(client):len(client)], name)
+		out, runErr := exec.Command(client[0], args...).CombinedOutput()
+		if runErr == nil {
+			return nil
+		}
+		log.Printf("%s %v: %v: %s", client[0], args, runErr, out)
+		err = fmt.Errorf("%s: %w", client[0], runErr)
+	}
+	return err
+}
+
+// GatewayReachable pings gw a few times and reports whether it answered.
+// Without ping there is no telling, so it leaves that to the user.
+func GatewayReachable(gw net.IP) bool {
+	if _, err := exec.LookPath("ping"); err != nil {
+		return true
+	}
+	return exec.Command("ping", "-n", "-q", "-c", "3", "-W", "1", gw.String()).Run() == nil
+}
@@ -0,0 +1,9 @@
+package panel
+
+import "image"
+
+// PackFrame converts img to the controller's column layout, as WriteFrame
+// does, for the tests in panel_test.
+func PackFrame(img *image.Gray) []byte {
+	return packColumns(packScanlines(img))
+}
@@ -0,0 +1,233 @@
+// Package panel drives the 128x64 monochrome LCD found on the front of
+// CheckPoint 4800 (and similar) appliances over its serial link.
+package panel
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const (
+	Width  = 128
+	Height = 64
+
+	bytesPerScanline = Width / 8
+	frameSize        = bytesPerScanline * Height
+	blockSize        = 64
+)
+
+// Key codes sent by the front panel buttons.
+const (
+	KeyHelp  = 0x41
+	KeyLeft  = 0x42
+	KeyEsc   = 0x43
+	KeyUp    = 0x44
+	KeyEnter = 0x45
+	KeyDown  = 0x46
+	KeyRight = 0x47
+)
+
+// DefaultSleep is the pause the controller needs between init commands.
+const DefaultSleep = 5 * time.Millisecond
+
+// DefaultMode returns the serial settings the LCD controller expects.
+func DefaultMode() *serial.Mode {
+	return &serial.Mode{
+		BaudRate: 115200,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+}
+
+// Panel owns the serial port of a front panel LCD. Frames are written from
+// one goroutine while another reads keys.
+type Panel struct {
+	sleep time.Duration
+
+	mu   sync.Mutex
+	port serial.Port
+	last []byte // column data of the last frame sent, nil if unknown
+}
+
+// Open opens the serial device with the given mode. A nil mode means
+// DefaultMode.
+func Open(device string, mode *serial.Mode) (*Panel, error) {
+	if mode == nil {
+		mode = DefaultMode()
+	}
+	port, err := serial.Open(device, mode)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open serial port %s: %w", device, err)
+	}
+	return &Panel{port: port, sleep: DefaultSleep}, nil
+}
+
+// SetInitDelay changes the pause between init commands, DefaultSleep by
+// default.
+func (p *Panel) SetInitDelay(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sleep = d
+}
+
+// Init resets the controller and clears the screen. It must be called once
+// before the first frame is written.
+func (p *Panel) Init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.init()
+}
+
+func (p *Panel) init() error {
+	p.last = nil
+	for _, cmd := range [][]byte{{0x1b, 0x40}, {0x0b}, {0x0c}} {
+		if err := p.write(cmd); err != nil {
+			return err
+		}
+		time.Sleep(p.sleep)
+	}
+	return nil
+}
+
+// Clear blanks the display.
+func (p *Panel) Clear() error {
+	blank := image.NewGray(image.Rect(0, 0, Width, Height))
+	draw.Draw(blank, blank.Bounds(), image.White, image.Point{}, draw.Src)
+	return p.WriteFrame(blank)
+}
+
+// WriteFrame sends a full frame to the display. Pixels darker than mid-grey
+// are lit; the image is expected to be Width x Height, anything outside that
+// area is ignored.
+//
+// Frames identical to the last one sent are skipped. The controller only
+// knows the whole-frame ESC G command, so a changed frame is always sent in
+// full.
+func (p *Panel) WriteFrame(img *image.Gray) error {
+	cols := packColumns(packScanlines(img))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.last != nil && bytes.Equal(cols, p.last) {
+		return nil
+	}
+	// Forget the last frame until this one is fully written, so a failed
+	// write is retried next time.
+	p.last = nil
+
+	if err := p.write([]byte{0x1b, 0x47}); err != nil {
+		return err
+	}
+	// The controller takes the frame as 64-byte blocks, even-indexed blocks
+	// (left half of each page) first, then the odd-indexed ones.
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < len(cols); i += blockSize {
+			if (i/blockSize)%2 != pass {
+				continue
+			}
+			if err := p.write(cols[i : i+blockSize]); err != nil {
+				return err
+			}
+		}
+	}
+	p.last = cols
+	return nil
+}
+
+// ReadKey waits up to timeout for a button press. ok is false if no key
+// arrived in time.
+func (p *Panel) ReadKey(timeout time.Duration) (key byte, ok bool, err error) {
+	// Don't hold the lock while blocked in Read; Close closing the port
+	// wakes the reader up with an error.
+	p.mu.Lock()
+	port := p.port
+	p.mu.Unlock()
+	if err := port.SetReadTimeout(timeout); err != nil {
+		return 0, false, err
+	}
+	buf := make([]byte, 1)
+	n, err := port.Read(buf)
+	if err != nil {
+		return 0, false, err
+	}
+	return buf[0], n == 1, nil
+}
+
+// Close releases the serial port.
+func (p *Panel) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.port.Close()
+}
+
+func (p *Panel) write(data []byte) error {
+	n, err := p.port.Write(data)
+	if err != nil {
+		return fmt.Errorf("serial write error: %w", err)
+	}
+	if n < len(data) {
+		return fmt.Errorf("serial write error: wrote only %d of %d bytes", n, len(data))
+	}
+	return nil
+}
+
+// packScanlines converts the image to 1bpp, MSB first, one scanline after
+// another from the top.
+func packScanlines(img *image.Gray) []byte {
+	b := img.Bounds()
+	scanlines := make([]byte, frameSize)
+	for y := 0; y < Height; y++ {
+		for xByte := 0; xByte < bytesPerScanline; xByte++ {
+			var v byte
+			for bit := 0; bit < 8; bit++ {
+				px, py := b.Min.X+xByte*8+bit, b.Min.Y+y
+				if !(image.Point{px, py}.In(b)) {
+					continue
+				}
+				if img.GrayAt(px, py).Y < 128 {
+					v |= 1 << (7 - bit)
+				}
+			}
+			scanlines[y*bytesPerScanline+xByte] = v
+		}
+	}
+	return scanlines
+}
+
+// findAddIdx maps a scanline offset (scanline number times bytes per
+// scanline) to the bit it sets in a column byte and the start of the
+// 128-byte page that holds it.
+func findAddIdx(scanlineNumTimes16 int) (addVal int, idxBase int) {
+	scanlineGroup := scanlineNumTimes16 / (8 * bytesPerScanline)
+	idxBase = scanlineGroup * Width
+	posInScanlineGroup := (scanlineNumTimes16 % (8 * bytesPerScanline)) / bytesPerScanline
+	addVal = 1 << uint(posInScanlineGroup)
+	return
+}
+
+// packColumns rearranges packed scanlines into the controller's layout:
+// eight pages of Width column bytes, each byte covering eight rows with the
+// top row in bit 0.
+func packColumns(scanlines []byte) []byte {
+	cols := make([]byte, frameSize)
+	for j := range bytesPerScanline {
+		for k := range Height {
+			scanlineBlockStartOffset := k * bytesPerScanline
+			currentByte := scanlines[scanlineBlockStartOffset+j]
+			add, idxBase := findAddIdx(scanlineBlockStartOffset)
+			targetColBase := idxBase + (j * 8)
+			for bit := 0; bit < 8; bit++ {
+				if currentByte&(0x80>>bit) != 0 {
+					cols[targetColBase+bit] += byte(add)
+				}
+			}
+		}
+	}
+	return cols
+}
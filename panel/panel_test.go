@@ -0,0 +1,137 @@
+package panel_test
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+	"time"
+
+	"lcdinator/panel"
+	"lcdinator/panel/paneltest"
+)
+
+// TestPackRoundTrip packs frames into columns and decodes them back the
+// way the fake device does.
+func TestPackRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := image.NewGray(image.Rect(0, 0, panel.Width, panel.Height))
+	for i := range random.Pix {
+		random.Pix[i] = uint8(rng.Intn(256))
+	}
+	frames := map[string]*image.Gray{"random": random}
+	// Single pixels catch bits and pages that are swapped consistently.
+	for _, p := range []image.Point{{0, 0}, {127, 0}, {0, 63}, {127, 63}, {9, 7}, {64, 8}, {100, 41}} {
+		img := image.NewGray(image.Rect(0, 0, panel.Width, panel.Height))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		img.SetGray(p.X, p.Y, color.Gray{Y: 0})
+		frames[p.String()] = img
+	}
+	for name, img := range frames {
+		got := paneltest.Decode(panel.PackFrame(img))
+		if n := paneltest.Diff(got, img); n != 0 {
+			t.Errorf("%s: %d pixels differ after the round trip", name, n)
+		}
+	}
+}
+
+func openFake(t *testing.T) (*paneltest.Device, *panel.Panel) {
+	t.Helper()
+	d, err := paneltest.New()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	p, err := panel.Open(d.Path(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	p.SetInitDelay(0)
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return d, p
+}
+
+func TestWriteFrame(t *testing.T) {
+	d, p := openFake(t)
+	img := image.NewGray(image.Rect(0, 0, panel.Width, panel.Height))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for x := 0; x < panel.Width; x++ {
+		img.SetGray(x, x/2, color.Gray{Y: 0})
+	}
+	if err := p.WriteFrame(img); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.WaitFrames(1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := paneltest.Diff(got, img); n != 0 {
+		t.Errorf("%d pixels differ", n)
+	}
+	if d.Inits() != 1 {
+		t.Errorf("got %d inits, want 1", d.Inits())
+	}
+}
+
+// TestWriteFrameSkipsUnchanged writes the same frame twice and then a
+// changed one. Serial data arrives in order, so if the repeat had been sent
+// the second frame the panel saw would be the first one again.
+func TestWriteFrameSkipsUnchanged(t *testing.T) {
+	d, p := openFake(t)
+	img := image.NewGray(image.Rect(0, 0, panel.Width, panel.Height))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	img.SetGray(10, 10, color.Gray{Y: 0})
+	for i := 0; i < 2; i++ {
+		if err := p.WriteFrame(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.WaitFrames(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := image.NewGray(img.Rect)
+	copy(changed.Pix, img.Pix)
+	changed.SetGray(20, 20, color.Gray{Y: 0})
+	if err := p.WriteFrame(changed); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.WaitFrames(2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := paneltest.Diff(got, changed); n != 0 {
+		t.Errorf("second frame differs from the changed one in %d pixels; the unchanged frame was sent again", n)
+	}
+	if n := d.Frames(); n != 2 {
+		t.Errorf("got %d frames, want 2", n)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	d, p := openFake(t)
+	if _, ok, err := p.ReadKey(50 * time.Millisecond); err != nil || ok {
+		t.Fatalf("ReadKey with no key pressed = %v, %v; want no key", ok, err)
+	}
+	for _, key := range []byte{panel.KeyUp, panel.KeyEnter, panel.KeyRight} {
+		if err := d.PressKey(key); err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := p.ReadKey(time.Second)
+		if err != nil || !ok || got != key {
+			t.Errorf("ReadKey = 0x%02X, %v, %v; want 0x%02X", got, ok, err, key)
+		}
+	}
+	if err := d.PressKey(0x30); err == nil {
+		t.Error("PressKey accepted a key the panel doesn't have")
+	}
+}
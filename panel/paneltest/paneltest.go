@@ -0,0 +1,353 @@
+// Package paneltest provides a fake front panel LCD backed by a
+// pseudo-terminal. The real panel.Panel is pointed at the slave side; the
+// fake decodes the ESC G frame stream written to it back into a bitmap and
+// can inject key presses, so screens and the column packing can be checked
+// against golden images without hardware.
+package paneltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"lcdinator/panel"
+)
+
+const (
+	frameSize = panel.Width * panel.Height / 8
+	blockSize = 64
+)
+
+// Device is a fake LCD on the master side of a pty pair.
+type Device struct {
+	master *os.File
+	slave  *os.File
+	path   string
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	inits   int
+	frames  int
+	cols    []byte
+	closed  bool
+	readErr error
+}
+
+// New opens a pty pair and starts decoding whatever is written to the slave.
+func New() (*Device, error) {
+	mfd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open /dev/ptmx: %w", err)
+	}
+	if err := unix.IoctlSetPointerInt(mfd, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(mfd)
+		return nil, fmt.Errorf("cannot unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetInt(mfd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(mfd)
+		return nil, fmt.Errorf("cannot get pty number: %w", err)
+	}
+	path := fmt.Sprintf("/dev/pts/%d", n)
+
+	// Keep our own handle on the slave so the master never sees EIO while
+	// the driver has the port closed, e.g. between reconnects.
+	sfd, err := unix.Open(path, unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		unix.Close(mfd)
+		return nil, fmt.Errorf("cannot open %s: %w", path, err)
+	}
+	if err := makeRaw(sfd); err != nil {
+		unix.Close(sfd)
+		unix.Close(mfd)
+		return nil, err
+	}
+
+	d := &Device{
+		master: os.NewFile(uintptr(mfd), "/dev/ptmx"),
+		slave:  os.NewFile(uintptr(sfd), path),
+		path:   path,
+	}
+	d.cond = sync.NewCond(&d.mu)
+	go d.run()
+	return d, nil
+}
+
+// Path is the serial device to hand to panel.Open.
+func (d *Device) Path() string {
+	return d.path
+}
+
+// PressKey sends a front panel key code (0x41-0x47) to the driver.
+func (d *Device) PressKey(key byte) error {
+	if key < panel.KeyHelp || key > panel.KeyRight {
+		return fmt.Errorf("key 0x%02X is not a front panel key", key)
+	}
+	_, err := d.master.Write([]byte{key})
+	return err
+}
+
+// Inits returns how many ESC @ resets the device has received.
+func (d *Device) Inits() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inits
+}
+
+// Frames returns how many complete frames the device has received.
+func (d *Device) Frames() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.frames
+}
+
+// Columns returns the last frame in the controller's column layout, with
+// the 64-byte blocks put back in address order. It is nil before the first
+// frame.
+func (d *Device) Columns() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return bytes.Clone(d.cols)
+}
+
+// Frame returns the last frame decoded into a bitmap, or nil before the
+// first frame.
+func (d *Device) Frame() *image.Gray {
+	cols := d.Columns()
+	if cols == nil {
+		return nil
+	}
+	return Decode(cols)
+}
+
+// WaitFrames blocks until at least n frames have been received and returns
+// the latest one.
+func (d *Device) WaitFrames(n int, timeout time.Duration) (*image.Gray, error) {
+	timer := time.AfterFunc(timeout, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.cond.Broadcast()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	d.mu.Lock()
+	for d.frames < n && !d.closed && d.readErr == nil && time.Now().Before(deadline) {
+		d.cond.Wait()
+	}
+	frames, err, cols := d.frames, d.readErr, bytes.Clone(d.cols)
+	d.mu.Unlock()
+
+	if frames < n {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("got %d of %d frames within %v", frames, n, timeout)
+	}
+	return Decode(cols), nil
+}
+
+// Close tears down the pty pair.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	d.cond.Broadcast()
+	d.mu.Unlock()
+	return errors.Join(d.master.Close(), d.slave.Close())
+}
+
+func (d *Device) run() {
+	const (
+		stateIdle = iota
+		stateEsc
+		stateFrame
+	)
+	state := stateIdle
+	frame := make([]byte, 0, frameSize)
+	buf := make([]byte, 4096)
+	for {
+		n, err := d.master.Read(buf)
+		if err != nil {
+			d.mu.Lock()
+			if !d.closed {
+				d.readErr = err
+			}
+			d.cond.Broadcast()
+			d.mu.Unlock()
+			return
+		}
+		for _, b := range buf[:n] {
+			switch state {
+			case stateIdle:
+				if b == 0x1b {
+					state = stateEsc
+				}
+			case stateEsc:
+				state = stateIdle
+				switch b {
+				case 0x40:
+					d.mu.Lock()
+					d.inits++
+					d.mu.Unlock()
+				case 0x47:
+					frame = frame[:0]
+					state = stateFrame
+				}
+			case stateFrame:
+				frame = append(frame, b)
+				if len(frame) == frameSize {
+					cols := unshuffleBlocks(frame)
+					d.mu.Lock()
+					d.cols = cols
+					d.frames++
+					d.cond.Broadcast()
+					d.mu.Unlock()
+					state = stateIdle
+				}
+			}
+		}
+	}
+}
+
+// unshuffleBlocks undoes the even-blocks-then-odd-blocks transmission order.
+func unshuffleBlocks(stream []byte) []byte {
+	cols := make([]byte, frameSize)
+	half := frameSize / blockSize / 2
+	for s := 0; s < frameSize/blockSize; s++ {
+		block := 2 * s
+		if s >= half {
+			block = 2*(s-half) + 1
+		}
+		copy(cols[block*blockSize:], stream[s*blockSize:(s+1)*blockSize])
+	}
+	return cols
+}
+
+// Decode turns a frame in the controller's column layout into a bitmap using
+// the same convention as the framebuffer: lit pixels black, the rest white.
+func Decode(cols []byte) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, panel.Width, panel.Height))
+	for page := 0; page < panel.Height/8; page++ {
+		for x := 0; x < panel.Width; x++ {
+			v := cols[page*panel.Width+x]
+			for bit := 0; bit < 8; bit++ {
+				c := color.Gray{Y: 255}
+				if v&(1<<bit) != 0 {
+					c = color.Gray{Y: 0}
+				}
+				img.SetGray(x, page*8+bit, c)
+			}
+		}
+	}
+	return img
+}
+
+// Threshold reduces an image to what the panel can show: pure black where a
+// pixel would be lit, white elsewhere.
+func Threshold(img *image.Gray) *image.Gray {
+	b := img.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if img.GrayAt(b.Min.X+x, b.Min.Y+y).Y < 128 {
+				out.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				out.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return out
+}
+
+// Diff counts the pixels whose lit state differs between a and b. Images of
+// different sizes differ everywhere.
+func Diff(a, b *image.Gray) int {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return max(a.Bounds().Dx()*a.Bounds().Dy(), b.Bounds().Dx()*b.Bounds().Dy())
+	}
+	ta, tb := Threshold(a), Threshold(b)
+	n := 0
+	for i := range ta.Pix {
+		if ta.Pix[i] != tb.Pix[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// ReadGolden loads a golden PNG.
+func ReadGolden(path string) (*image.Gray, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	b := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return gray, nil
+}
+
+// WriteGolden saves img, thresholded, as a golden PNG.
+func WriteGolden(path string, img *image.Gray) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, Threshold(img)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CheckGolden compares got with the golden image at path. When the
+// PANELTEST_UPDATE environment variable is set the golden file is
+// (re)written instead.
+func CheckGolden(path string, got *image.Gray) error {
+	if os.Getenv("PANELTEST_UPDATE") != "" {
+		return WriteGolden(path, got)
+	}
+	want, err := ReadGolden(path)
+	if err != nil {
+		return err
+	}
+	if n := Diff(got, want); n != 0 {
+		return fmt.Errorf("%s: %d pixels differ", path, n)
+	}
+	return nil
+}
+
+func makeRaw(fd int) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("cannot get pty settings: %w", err)
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB
+	t.Cflag |= unix.CS8
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return fmt.Errorf("cannot set pty to raw mode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// PINScreen asks for the PIN before a guarded action runs. The PIN is
+// entered one digit at a time: UP/DOWN change the current digit, RIGHT
+// moves on to the next one, LEFT erases back to the previous one and ENTER
+// checks it. Digits already entered show as stars. ESC gives up without
+// running the action.
+type PINScreen struct {
+	nav       *Navigator
+	lock      *Lock
+	onUnlock  func()
+	digits    []byte
+	checking  bool // a check is running; keys are ignored until it's done
+	closed    bool
+	message   string // why the last PIN was refused
+	marquee   Marquee
+	animating bool
+}
+
+func NewPINScreen(nav *Navigator, lock *Lock, onUnlock func()) *PINScreen {
+	return &PINScreen{nav: nav, lock: lock, onUnlock: onUnlock, digits: []byte{'0'}}
+}
+
+func (s *PINScreen) Animating() bool {
+	return s.animating
+}
+
+func (s *PINScreen) Draw(fb *image.Gray) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: face}
+	d.Dot = fixed.P(0, Baseline(face, 0, 16))
+	d.DrawString("Enter PIN")
+
+	text := strings.Repeat("*", len(s.digits)-1) + string(s.digits[len(s.digits)-1])
+	x := (fb.Bounds().Dx() - d.MeasureString(text).Ceil()) / 2
+	d.Dot = fixed.P(x, Baseline(face, 24, 16))
+	d.DrawString(text[:len(text)-1])
+	// The digit being entered is shown inverted.
+	x = d.Dot.X.Ceil()
+	w := d.MeasureString(text[len(text)-1:]).Ceil()
+	FillRect(fb, image.Rect(x-1, 25, x+w+1, 39), color.Gray{Y: 0})
+	d.Src = image.White
+	d.DrawString(text[len(text)-1:])
+	d.Src = image.Black
+
+	message := s.message
+	switch {
+	case s.checking:
+		message = "Checking..."
+	case message == "":
+		message = "Enter: unlock"
+	}
+	s.animating = s.marquee.Draw(d, image.Rect(0, 48, fb.Bounds().Dx(), 64), Baseline(face, 48, 16), message)
+}
+
+func (s *PINScreen) HandleKey(key byte) bool {
+	if s.checking {
+		// ESC still gives up; the check finishes in the background.
+		if key == KEY_ESC {
+			s.close()
+		}
+		return true
+	}
+	last := len(s.digits) - 1
+	switch key {
+	case KEY_UP:
+		s.digits[last] = '0' + (s.digits[last]-'0'+1)%10
+	case KEY_DOWN:
+		s.digits[last] = '0' + (s.digits[last]-'0'+9)%10
+	case KEY_RIGHT:
+		if len(s.digits) < maxPINLength {
+			s.digits = append(s.digits, '0')
+		}
+	case KEY_LEFT:
+		if last > 0 {
+			s.digits = s.digits[:last]
+		}
+	case KEY_ENTER:
+		s.check()
+	case KEY_ESC:
+		s.close()
+	default:
+		return false
+	}
+	return true
+}
+
+// check tries the PIN off the UI goroutine, since that takes a while, and
+// runs the guarded action if it was right.
+func (s *PINScreen) check() {
+	pin := string(s.digits)
+	s.digits = []byte{'0'}
+	s.checking = true
+	go func() {
+		err := s.lock.Try(pin)
+		s.nav.Do(func() {
+			s.checking = false
+			if err != nil {
+				s.message = err.Error()
+				s.marquee.Reset()
+				return
+			}
+			if !s.closed {
+				s.close()
+				s.onUnlock()
+			}
+		})
+	}()
+}
+
+func (s *PINScreen) close() {
+	s.closed = true
+	s.nav.Remove(s)
+}
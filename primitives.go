@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// FillRect fills r with c, clipped to the framebuffer.
+func FillRect(fb *image.Gray, r image.Rectangle, c color.Gray) {
+	draw.Draw(fb, r.Intersect(fb.Bounds()), &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// DrawRect draws a one pixel outline just inside r.
+func DrawRect(fb *image.Gray, r image.Rectangle, c color.Gray) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		fb.SetGray(x, r.Min.Y, c)
+		fb.SetGray(x, r.Max.Y-1, c)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		fb.SetGray(r.Min.X, y, c)
+		fb.SetGray(r.Max.X-1, y, c)
+	}
+}
+
+// DrawProgressBar draws an outlined bar in r filled from the left to frac,
+// clamped to 0..1.
+func DrawProgressBar(fb *image.Gray, r image.Rectangle, frac float64) {
+	DrawRect(fb, r, color.Gray{Y: 0})
+	if frac < 0 {
+		frac = 0
+	}
+	if frac > 1 {
+		frac = 1
+	}
+	inner := r.Inset(1)
+	inner.Max.X = inner.Min.X + int(float64(inner.Dx())*frac+0.5)
+	FillRect(fb, inner, color.Gray{Y: 0})
+}
+
+// DrawGauge draws "label [bar] P%" in the row r: the label on the left,
+// the bar starting labelWidth pixels in and the percentage right-aligned.
+// A negative frac means unknown and shows "n/a".
+func DrawGauge(fb *image.Gray, d *font.Drawer, r image.Rectangle, label string, labelWidth int, frac float64) {
+	baseline := Baseline(d.Face, r.Min.Y, r.Dy())
+	d.Dot = fixed.P(r.Min.X, baseline)
+	d.DrawString(label)
+
+	percent := "n/a"
+	if frac >= 0 {
+		percent = fmt.Sprintf("%.0f%%", 100*frac)
+	}
+	barHeight := d.Face.Metrics().Ascent.Ceil()
+	top := baseline - barHeight
+	barRight := r.Max.X - d.MeasureString(" 100%").Ceil()
+	DrawProgressBar(fb, image.Rect(r.Min.X+labelWidth, top, barRight, top+barHeight), frac)
+	d.Dot = fixed.P(r.Max.X-d.MeasureString(percent).Ceil(), baseline)
+	d.DrawString(percent)
+}
+
+// DrawSparkline graphs values as filled columns, one pixel wide, rising
+// from the bottom of r. The newest value is at the right edge; values that
+// don't fit scroll off the left. Values are scaled so max reaches the top
+// of r; with max <= 0 the largest value does.
+func DrawSparkline(fb *image.Gray, r image.Rectangle, values []float64, max float64) {
+	if len(values) > r.Dx() {
+		values = values[len(values)-r.Dx():]
+	}
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+		if max <= 0 {
+			return
+		}
+	}
+	x := r.Max.X - len(values)
+	for _, v := range values {
+		h := int(v/max*float64(r.Dy()) + 0.5)
+		if h > r.Dy() {
+			h = r.Dy()
+		}
+		if v > 0 && h == 0 {
+			h = 1 // show that something happened
+		}
+		FillRect(fb, image.Rect(x, r.Max.Y-h, x+1, r.Max.Y), color.Gray{Y: 0})
+		x++
+	}
+}
+
+// Baseline returns the baseline that vertically centres a line of face in a
+// row of the given height starting at top.
+func Baseline(face font.Face, top, height int) int {
+	m := face.Metrics()
+	return top + (height-m.Height.Ceil())/2 + m.Ascent.Ceil()
+}
+
+// WrapText breaks text into lines no wider than width pixels, splitting on
+// spaces. Words longer than a line are split at the character where they
+// overflow. Every line gets at least one character, so a character wider
+// than width sticks out rather than being dropped.
+func WrapText(face font.Face, text string, width int) []string {
+	limit := fixed.I(width)
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.MeasureString(face, candidate) <= limit {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for runes := []rune(word); len(runes) > 1 && font.MeasureString(face, word) > limit; runes = []rune(word) {
+			n := len(runes) - 1
+			for n > 1 && font.MeasureString(face, string(runes[:n])) > limit {
+				n--
+			}
+			lines = append(lines, string(runes[:n]))
+			word = string(runes[n:])
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
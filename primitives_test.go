@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestWrapText(t *testing.T) {
+	// basicfont.Face7x13 is 7 pixels per character.
+	face := basicfont.Face7x13
+	for _, tt := range []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 70, nil},
+		{"Reboot the system?", 70, []string{"Reboot the", "system?"}},
+		{"Stop NetworkManager.service?", 70, []string{"Stop", "NetworkMan", "ager.servi", "ce?"}},
+		{"Größenänderung", 35, []string{"Größe", "nände", "rung"}},
+		// Narrower than a character: one character per line.
+		{"ab c", 3, []string{"a", "b", "c"}},
+		{"ab", 0, []string{"a", "b"}},
+	} {
+		if got := WrapText(face, tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// ProcessScreen lists processes by CPU usage. UP/DOWN scroll, LEFT sends
+// SIGTERM and RIGHT SIGKILL to the selected process, and ENTER renices it,
+// each after a confirmation.
+type ProcessScreen struct {
+	nav   *Navigator
+	face  font.Face
+	list  *ListView
+	procs []ProcessCPU // of the list items
+}
+
+func NewProcessScreen(nav *Navigator) *ProcessScreen {
+	s := &ProcessScreen{nav: nav, face: defaultFace("5x7")}
+	lineHeight := s.face.Metrics().Height.Ceil() + 1
+	s.list = NewListView(image.Rect(0, lineHeight, 128, 64), lineHeight)
+	s.list.Face = s.face
+	return s
+}
+
+func (s *ProcessScreen) Draw(fb *image.Gray) {
+	s.refresh()
+	d := &font.Drawer{Dst: fb, Src: image.Black, Face: s.face}
+	d.Dot = fixed.P(d.MeasureString("> ").Ceil(), s.face.Metrics().Ascent.Ceil())
+	d.DrawString(" CPU%   PID NAME")
+	s.list.Draw(fb)
+}
+
+// refresh reloads the processes, keeping the cursor on the same process
+// as they change places.
+func (s *ProcessScreen) refresh() {
+	s.procs = collector.Snapshot().Processes
+	items := make([]ListItem, len(s.procs))
+	for i, p := range s.procs {
+		items[i] = ListItem{Label: fmt.Sprintf("%5.1f %5d %s", p.CPU, p.PID, p.Name), Key: strconv.Itoa(p.PID)}
+	}
+	s.list.SetItems(items)
+}
+
+func (s *ProcessScreen) selected() (ProcessCPU, bool) {
+	i := s.list.Selected()
+	if i < 0 || i >= len(s.procs) {
+		return ProcessCPU{}, false
+	}
+	return s.procs[i], true
+}
+
+func (s *ProcessScreen) Needs() Need {
+	return NeedProcesses
+}
+
+func (s *ProcessScreen) Animating() bool {
+	return s.list.Animating()
+}
+
+func (s *ProcessScreen) HandleKey(key byte) bool {
+	if s.list.HandleKey(key) {
+		return true
+	}
+	p, ok := s.selected()
+	if !ok {
+		return false
+	}
+	switch key {
+	case KEY_LEFT:
+		s.confirmSignal(p.ProcStat, syscall.SIGTERM)
+		return true
+	case KEY_RIGHT:
+		s.confirmSignal(p.ProcStat, syscall.SIGKILL)
+		return true
+	case KEY_ENTER:
+		msg := fmt.Sprintf("Nice for %s (%d)", p.Name, p.PID)
+		s.nav.Push(NewValueDialog(s.nav, msg, p.Nice, -20, 19, func(nice int) {
+			panelLock.Guard(s.nav, func() {
+				if err := ReniceProcess(p.ProcStat, nice); err != nil {
+					log.Printf("Renice %d: %v", p.PID, err)
+				}
+			})()
+		}))
+		return true
+	}
+	return false
+}
+
+// confirmSignal asks before sending sig to p.
+func (s *ProcessScreen) confirmSignal(p ProcStat, sig syscall.Signal) {
+	// The dialog fits two lines, so use the short signal names.
+	name := "TERM"
+	if sig == syscall.SIGKILL {
+		name = "KILL"
+	}
+	s.nav.Push(NewConfirmDialog(s.nav, fmt.Sprintf("%s %s (%d)?", name, p.Name, p.PID), panelLock.Guard(s.nav, func() {
+		if err := SignalProcess(p, sig); err != nil {
+			log.Printf("SIG%s %d: %v", name, p.PID, err)
+		}
+	})))
+}
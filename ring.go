@@ -0,0 +1,53 @@
+package main
+
+// Ring is a fixed-size buffer of samples that overwrites the oldest one
+// when full.
+type Ring struct {
+	data  []float64
+	start int // index of the oldest sample
+	n     int
+}
+
+func NewRing(size int) *Ring {
+	return &Ring{data: make([]float64, size)}
+}
+
+// Push adds v as the newest sample.
+func (r *Ring) Push(v float64) {
+	if len(r.data) == 0 {
+		return
+	}
+	if r.n < len(r.data) {
+		r.data[(r.start+r.n)%len(r.data)] = v
+		r.n++
+		return
+	}
+	r.data[r.start] = v
+	r.start = (r.start + 1) % len(r.data)
+}
+
+func (r *Ring) Len() int {
+	return r.n
+}
+
+// At returns the i-th sample, oldest first.
+func (r *Ring) At(i int) float64 {
+	return r.data[(r.start+i)%len(r.data)]
+}
+
+// Last returns the newest sample, or 0 if there is none.
+func (r *Ring) Last() float64 {
+	if r.n == 0 {
+		return 0
+	}
+	return r.At(r.n - 1)
+}
+
+// Values returns a copy of the samples, oldest first.
+func (r *Ring) Values() []float64 {
+	v := make([]float64, r.n)
+	for i := range v {
+		v[i] = r.At(i)
+	}
+	return v
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"image"
-	"sync/atomic"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
@@ -17,55 +22,132 @@ func min(a, b int) int {
 	return b
 }
 
-var globalMenuIndex *int32
-var globalInDialog *int32
-var globalDialogType *int32
-var globalNetIfIndex *int32
-var globalServiceIndex *int32
-var globalServiceAction *int32     // 0 = none, 1 = stop, 2 = restart
-var globalServiceViewOffset *int32 // Tracks the first visible service index
-var globalDialogResult *int32
-var globalRequestedScreen *int32
-
-type SystemInfoScreen struct{}
-type AboutScreen struct{}
-type MenuScreen struct{}
-type NetworkInfoScreen struct{}
-type ServiceManagerScreen struct{}
+type SystemInfoScreen struct {
+	DiskPath string `json:"disk"` // filesystem shown on the DSK line
 
+	screenFace
+}
+type AboutScreen struct{}
+type MenuScreen struct {
+	nav       *Navigator
+	services  Screen
+	processes Screen
+	list      *ListView
+}
+type NetworkInfoScreen struct {
+	Hide []string `json:"hide"` // interface names or glob patterns to leave out
+
+	screenFace
+	nav       *Navigator
+	index     int
+	page      int
+	marquees  [4]Marquee // one per row
+	animating bool
+}
 type Screen interface {
 	Draw(fb *image.Gray)
 	HandleKey(key byte) bool
 }
 
-func (s *SystemInfoScreen) Draw(fb *image.Gray) {
-	DrawIcon(fb, 0, 2, IconCPU)
-	DrawIcon(fb, 0, 18, IconRAM)
-	DrawIcon(fb, 0, 34, IconDisk)
-	DrawIcon(fb, 0, 50, IconClock)
+// screenFace is embedded in screens whose font can be chosen in the config.
+type screenFace struct {
+	face font.Face
+}
 
-	face := basicfont.Face7x13
+func (s *screenFace) SetFace(face font.Face) {
+	s.face = face
+}
+
+// Face returns the chosen font, basicfont.Face7x13 by default.
+func (s *screenFace) Face() font.Face {
+	if s.face == nil {
+		return basicfont.Face7x13
+	}
+	return s.face
+}
+
+// screenFactories builds the screens that can be listed in the config file
+// for the rotation. Per-screen options are decoded straight into the
+// screen's exported fields. Screens that need LEFT/RIGHT for themselves,
+// like the service manager, are opened from the menu instead.
+var screenFactories = map[string]func(nav *Navigator) Screen{
+	"system":  func(nav *Navigator) Screen { return &SystemInfoScreen{DiskPath: "/"} },
+	"network": func(nav *Navigator) Screen { return &NetworkInfoScreen{nav: nav} },
+	"graph":   func(nav *Navigator) Screen { return NewGraphScreen() },
+	"cpu":     func(nav *Navigator) Screen { return NewCPUScreen() },
+	"disk":    func(nav *Navigator) Screen { return NewDiskScreen() },
+	"memory":  func(nav *Navigator) Screen { return NewMemoryScreen() },
+}
+
+func newScreen(nav *Navigator, sc ScreenConfig) (Screen, error) {
+	factory, ok := screenFactories[sc.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown screen %q", sc.Name)
+	}
+	s := factory(nav)
+	if sc.Font != "" {
+		fs, ok := s.(interface{ SetFace(font.Face) })
+		if !ok {
+			return nil, fmt.Errorf("font can't be changed")
+		}
+		face, err := LoadFace(sc.Font)
+		if err != nil {
+			return nil, err
+		}
+		fs.SetFace(face)
+	}
+	if len(sc.Options) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(sc.Options))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(s); err != nil {
+			return nil, fmt.Errorf("options: %w", err)
+		}
+	}
+	return s, nil
+}
+
+func screenNames() []string {
+	names := make([]string, 0, len(screenFactories))
+	for name := range screenFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *SystemInfoScreen) Draw(fb *image.Gray) {
+	face := s.Face()
 	d := &font.Drawer{
 		Dst:  fb,
 		Src:  image.Black,
 		Face: face,
 	}
+	// Four rows of 16px, each with an icon in front of the text.
+	row := func(i int, icon [8]byte, text string) {
+		baseline := Baseline(face, 16*i, 16)
+		DrawIcon(fb, 0, 16*i+2, icon)
+		d.Dot = fixed.P(10, baseline)
+		d.DrawString(text)
+	}
 
-	cpuUsage := GetCPUUsage()
-	d.Dot = fixed.P(10, 12)
-	d.DrawString(fmt.Sprintf("CPU: %2.0f%%", cpuUsage))
-
-	memUsed, memTotal := GetMemInfo()
-	d.Dot = fixed.P(10, 28)
-	d.DrawString(fmt.Sprintf("RAM: %d/%d MB", memUsed, memTotal))
-
-	diskUsed, diskTotal := GetDiskInfo()
-	d.Dot = fixed.P(10, 44)
-	d.DrawString(fmt.Sprintf("DSK: %d/%d GB", diskUsed, diskTotal))
+	snap := collector.Snapshot()
+	row(0, IconCPU, fmt.Sprintf("CPU: %2.0f%%", snap.CPU))
+	row(1, IconRAM, fmt.Sprintf("RAM: %d/%d MB", snap.Mem.Used()>>20, snap.Mem.Total>>20))
+	if disk, ok := collector.Disk(s.DiskPath); ok {
+		row(2, IconDisk, fmt.Sprintf("DSK: %s/%s", formatBytes(disk.Used), formatBytes(disk.Total)))
+	} else {
+		row(2, IconDisk, "DSK: -")
+	}
+	row(3, IconClock, fmt.Sprintf("UPT: %s", snap.Uptime))
 
-	uptime := GetUptime()
-	d.Dot = fixed.P(10, 60)
-	d.DrawString(fmt.Sprintf("UPT: %s", uptime))
+	// Failed services get a badge at the right of the first row.
+	if n := systemd.Failed(); n > 0 {
+		count := strconv.Itoa(n)
+		x := fb.Bounds().Dx() - d.MeasureString(count).Ceil()
+		d.Dot = fixed.P(x, Baseline(face, 0, 16))
+		d.DrawString(count)
+		DrawIcon(fb, x-10, 2, IconFailed)
+	}
 }
 
 func (s *AboutScreen) Draw(fb *image.Gray) {
@@ -83,220 +165,192 @@ func (s *AboutScreen) Draw(fb *image.Gray) {
 	d.DrawString("version 1")
 }
 
-func (s *MenuScreen) Draw(fb *image.Gray) {
-	face := basicfont.Face7x13
-	d := &font.Drawer{
-		Dst:  fb,
-		Src:  image.Black,
-		Face: face,
+var menuItems = []string{"Services", "Processes", "Shutdown", "Reboot"}
+
+func NewMenuScreen(nav *Navigator, cfg *Config) *MenuScreen {
+	m := &MenuScreen{
+		nav:       nav,
+		services:  NewServiceManagerScreen(nav, cfg.Services),
+		processes: NewProcessScreen(nav),
+		list:      NewListView(image.Rect(0, 0, 128, 64), 14),
 	}
-	menuItems := []string{"Shutdown", "Reboot"}
+	items := make([]ListItem, len(menuItems))
 	for i, item := range menuItems {
-		y := 16 + i*20
-		if globalMenuIndex != nil && *globalMenuIndex == int32(i) {
-			d.Dot = fixed.P(0, y)
-			d.DrawString("> " + item)
-		} else {
-			d.Dot = fixed.P(0, y)
-			d.DrawString("  " + item)
-		}
-	}
-	// Draw confirmation dialog overlay if needed
-	if globalInDialog != nil && *globalInDialog == 1 && globalDialogType != nil {
-		msg := "Are you sure?"
-		if *globalDialogType == 1 {
-			msg = "Shutdown? (OK/ESC)"
-		} else if *globalDialogType == 2 {
-			msg = "Reboot? (OK/ESC)"
-		}
-		d.Dot = fixed.P(10, 55)
-		d.DrawString(msg)
+		items[i] = ListItem{Label: item}
 	}
+	m.list.SetItems(items)
+	return m
 }
 
-func (s *NetworkInfoScreen) Draw(fb *image.Gray) {
-	face := basicfont.Face7x13
-	d := &font.Drawer{
-		Dst:  fb,
-		Src:  image.Black,
-		Face: face,
-	}
-	ifaces, _ := GetNetworkInterfaces()
-	idx := 0
-	if globalNetIfIndex != nil {
-		idx = int(*globalNetIfIndex)
-	}
-	numIfaces := len(ifaces)
-	if numIfaces == 0 {
-		d.Dot = fixed.P(0, 16)
-		d.DrawString("No interfaces")
-		return
-	}
-	if idx < 0 || idx >= numIfaces {
-		idx = 0
-	}
-	iface := ifaces[idx]
-	// Name and status, with (idx/n)
-	DrawIcon(fb, 0, 4, IconPlug)
-	d.Dot = fixed.P(10, 12)
-	d.DrawString(fmt.Sprintf("%s (%d/%d)", iface.Name, idx+1, numIfaces))
-	// IP
-	if iface.IP != "" {
-		DrawIcon(fb, 0, 20, IconNet)
-		d.Dot = fixed.P(10, 28)
-		d.DrawString(fmt.Sprintf("IP: %s", iface.IP))
-	} else {
-		DrawIcon(fb, 0, 20, IconNetError)
-		d.Dot = fixed.P(10, 28)
-		status := "Down"
-		if iface.Up {
-			status = "Up"
-		}
-		d.DrawString(fmt.Sprintf("%s, no IP", status))
-	}
-	// Bandwidth (always show, even if 0)
-	DrawIcon(fb, 0, 36, IconArrowUp)
-	d.Dot = fixed.P(10, 44)
-	d.DrawString(fmt.Sprintf("RX: %d KB/s", iface.RxRate/1024))
-	DrawIcon(fb, 0, 52, IconArrowDown)
-	d.Dot = fixed.P(10, 60)
-	d.DrawString(fmt.Sprintf("TX: %d KB/s", iface.TxRate/1024))
+func (s *MenuScreen) Draw(fb *image.Gray) {
+	s.list.Draw(fb)
 }
 
-func (s *ServiceManagerScreen) Draw(fb *image.Gray) {
-	face := basicfont.Face7x13
+func (s *NetworkInfoScreen) Draw(fb *image.Gray) {
+	face := s.Face()
 	d := &font.Drawer{
 		Dst:  fb,
 		Src:  image.Black,
 		Face: face,
 	}
-	services := GetRunningServices()
-	numServices := len(services)
-
-	selectedIndex := 0
-	if globalServiceIndex != nil {
-		selectedIndex = int(atomic.LoadInt32(globalServiceIndex))
-	}
-
-	viewOffset := 0
-	if globalServiceViewOffset != nil { // Ensure it's initialized
-		viewOffset = int(atomic.LoadInt32(globalServiceViewOffset))
-	}
-
-	if numServices == 0 {
+	ifaces := s.interfaces()
+	s.animating = false
+	if len(ifaces) == 0 {
 		d.Dot = fixed.P(0, 16)
-		d.DrawString("No services found")
+		d.DrawString("No interfaces")
 		return
 	}
-
-	// Clamp selectedIndex (should be managed by HandleKey, but good for safety here too)
-	if selectedIndex < 0 {
-		selectedIndex = 0
+	pages := netPages(ifaces[s.index], s.index, len(ifaces))
+	if s.page >= len(pages) {
+		s.page = 0
 	}
-	if selectedIndex >= numServices {
-		selectedIndex = numServices - 1
-	}
-
-	const maxItemsOnScreen = 3
-	const itemHeight = 16
-	const listStartY = 12 // Y position where the list of items starts
-
-	// Adjust viewOffset to ensure it's valid and the selected item is visible.
-	if numServices <= maxItemsOnScreen {
-		viewOffset = 0
-	} else {
-		maxPossibleViewOffset := numServices - maxItemsOnScreen
-		// Ensure selected item is visible
-		if selectedIndex < viewOffset {
-			viewOffset = selectedIndex
-		} else if selectedIndex >= viewOffset+maxItemsOnScreen {
-			viewOffset = selectedIndex - maxItemsOnScreen + 1
+	width := fb.Bounds().Dx()
+	// Four rows of 16px, each with an optional icon in front of the text.
+	for i, row := range pages[s.page] {
+		baseline := Baseline(face, 16*i, 16)
+		if row.icon != nil {
+			DrawIcon(fb, 0, baseline-8, *row.icon)
 		}
-		// Clamp viewOffset to its valid range
-		if viewOffset < 0 {
-			viewOffset = 0
+		if s.marquees[i].Draw(d, image.Rect(10, 16*i, width, 16*(i+1)), baseline, row.text) {
+			s.animating = true
 		}
-		if viewOffset > maxPossibleViewOffset {
-			viewOffset = maxPossibleViewOffset
-		}
-	}
-	if globalServiceViewOffset != nil { // Store the corrected/validated viewOffset
-		atomic.StoreInt32(globalServiceViewOffset, int32(viewOffset))
 	}
+}
 
-	// Draw menu of services
-	for i := 0; i < maxItemsOnScreen; i++ {
-		actualServiceIndex := viewOffset + i
-		if actualServiceIndex >= numServices {
-			break // No more services to draw
-		}
-		svc := services[actualServiceIndex]
-		yPos := listStartY + i*itemHeight
+type netRow struct {
+	icon *[8]byte
+	text string
+}
 
-		prefix := "  "
-		if actualServiceIndex == selectedIndex {
-			prefix = "> "
+// netAddrsPerPage is how many addresses fit under the header of a page.
+const netAddrsPerPage = 3
+
+// netPages lays out what there is to show about an interface as pages of
+// four rows: a summary, the wireless link if any, its addresses, link
+// details, error counters and the way into its configuration.
+func netPages(iface NetInterfaceInfo, idx, n int) [][4]netRow {
+	summary := [4]netRow{
+		{&IconPlug, fmt.Sprintf("%s (%d/%d)", iface.Name, idx+1, n)},
+		{},
+		{&IconArrowUp, fmt.Sprintf("RX: %d KB/s", iface.RxRate/1024)},
+		{&IconArrowDown, fmt.Sprintf("TX: %d KB/s", iface.TxRate/1024)},
+	}
+	ip := iface.IPv4()
+	if ip == "" && len(iface.Addrs) > 0 {
+		ip, _, _ = strings.Cut(iface.Addrs[0], "/")
+	}
+	switch {
+	case ip != "":
+		summary[1] = netRow{&IconNet, "IP: " + ip}
+	case !iface.Up:
+		summary[1] = netRow{&IconNetError, "Down, no IP"}
+	default:
+		summary[1] = netRow{&IconNetError, "Up, no IP"}
+	}
+	pages := [][4]netRow{summary}
+
+	if w := iface.Wireless; w != nil {
+		page := [4]netRow{{&IconNet, iface.Name + " wireless"}, {text: "Not connected"}}
+		if w.SSID != "" {
+			page[1].text = "SSID " + w.SSID
 		}
-		// Truncate service name if too long
-		maxServiceNameLen := (fb.Bounds().Max.X / 7) - len(prefix) - 3 // Approx chars, 7px font, space for scrollbar
-		if len(svc) > maxServiceNameLen && maxServiceNameLen > 0 {
-			svc = svc[:maxServiceNameLen-1] + "…"
+		stations := fmt.Sprintf("%d stations", w.Stations)
+		if w.Stations == 1 {
+			stations = "1 station"
 		}
-
-		d.Dot = fixed.P(0, yPos)
-		d.DrawString(prefix + svc)
+		page[2].text = stations
+		if w.Signal != 0 {
+			page[2].text = fmt.Sprintf("%d dBm, %s", w.Signal, stations)
+		}
+		if w.TxBitrate > 0 || w.RxBitrate > 0 {
+			page[3].text = fmt.Sprintf("TX %s RX %s Mb/s", formatBitrate(w.TxBitrate), formatBitrate(w.RxBitrate))
+		}
+		pages = append(pages, page)
 	}
 
-	// Draw scrollbar
-	itemAreaHeight := maxItemsOnScreen * itemHeight
-	scrollbarX := fb.Bounds().Max.X - 3 // Position for a 2px wide scrollbar
-
-	if numServices > maxItemsOnScreen {
-		thumbHeightRatio := float64(maxItemsOnScreen) / float64(numServices)
-		thumbHeightPixels := int(thumbHeightRatio * float64(itemAreaHeight))
-		if thumbHeightPixels < 3 { // Min thumb height
-			thumbHeightPixels = 3
-		}
-		if thumbHeightPixels > itemAreaHeight {
-			thumbHeightPixels = itemAreaHeight
+	addrPages := (len(iface.Addrs) + netAddrsPerPage - 1) / netAddrsPerPage
+	for p := 0; p < addrPages; p++ {
+		page := [4]netRow{{&IconNet, fmt.Sprintf("%s addr %d/%d", iface.Name, p+1, addrPages)}}
+		for i, addr := range iface.Addrs[p*netAddrsPerPage : min((p+1)*netAddrsPerPage, len(iface.Addrs))] {
+			page[i+1] = netRow{text: addr}
 		}
+		pages = append(pages, page)
+	}
+
+	mac := iface.MAC
+	if mac == "" {
+		mac = "none"
+	}
+	carrier := "no carrier"
+	if iface.Carrier {
+		carrier = "carrier"
+	}
+	speed := "Speed unknown"
+	if iface.Speed > 0 {
+		speed = fmt.Sprintf("%dMb/s %s", iface.Speed, iface.Duplex)
+	}
+	pages = append(pages, [4]netRow{
+		{&IconPlug, iface.Name + " link"},
+		{text: "MAC " + mac},
+		{text: fmt.Sprintf("MTU %d, %s", iface.MTU, carrier)},
+		{text: speed},
+	}, [4]netRow{
+		{&IconNetError, iface.Name + " errors"},
+		{text: fmt.Sprintf("Err RX %d TX %d", iface.RxErrors, iface.TxErrors)},
+		{text: fmt.Sprintf("Drop RX %d TX %d", iface.RxDropped, iface.TxDropped)},
+		{text: fmt.Sprintf("RX %s TX %s", formatBytes(iface.RxBytes), formatBytes(iface.TxBytes))},
+	})
+	// The last page opens the InterfaceScreen.
+	pages = append(pages, [4]netRow{
+		{&IconPlug, iface.Name + " config"},
+		{text: "Enter: up/down,"},
+		{text: "DHCP, static IP"},
+	})
+	return pages
+}
 
-		scrollableRangePixels := itemAreaHeight - thumbHeightPixels
-		totalScrollableItemPositions := numServices - maxItemsOnScreen
+// formatBitrate formats a rate in 100 kbit/s as Mb/s, e.g. "72.2".
+func formatBitrate(rate int) string {
+	return fmt.Sprintf("%d.%d", rate/10, rate%10)
+}
 
-		var thumbTopRelativeY int
-		if totalScrollableItemPositions > 0 {
-			thumbTopRelativeY = (viewOffset * scrollableRangePixels) / totalScrollableItemPositions
-		} else {
-			thumbTopRelativeY = 0
-		}
-		thumbAbsoluteTopY := listStartY + thumbTopRelativeY
-
-		for yOffset := 0; yOffset < thumbHeightPixels; yOffset++ {
-			pixelY := thumbAbsoluteTopY + yOffset
-			if pixelY >= listStartY && pixelY < listStartY+itemAreaHeight {
-				fb.Set(scrollbarX, pixelY, image.Black)
-				fb.Set(scrollbarX+1, pixelY, image.Black)
-			}
+// interfaces returns the interfaces to show, without the hidden ones, and
+// keeps the index in range as interfaces come and go.
+func (s *NetworkInfoScreen) interfaces() []NetInterfaceInfo {
+	ifaces := collector.Snapshot().Interfaces
+	shown := ifaces[:0]
+	for _, iface := range ifaces {
+		if !matchAny(s.Hide, iface.Name) {
+			shown = append(shown, iface)
 		}
 	}
+	if s.index >= len(shown) {
+		s.index = max(len(shown)-1, 0)
+		s.page = 0
+	}
+	return shown
+}
 
-	// Draw action dialog if needed
-	if globalServiceAction != nil && atomic.LoadInt32(globalServiceAction) != 0 {
-		action := ""
-		currentAction := atomic.LoadInt32(globalServiceAction)
-		if currentAction == 1 {
-			action = "Stop"
-		} else if currentAction == 2 {
-			action = "Restart"
-		}
-		if selectedIndex >= 0 && selectedIndex < numServices {
-			d.Dot = fixed.P(0, 60)
-			d.DrawString(fmt.Sprintf("%s %s? (OK/ESC)", action, services[selectedIndex]))
-		} else {
-			atomic.StoreInt32(globalServiceAction, 0) // Clear action if index is bad
+// matchAny reports whether name matches any of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
 		}
 	}
+	return false
+}
+
+func (s *NetworkInfoScreen) Needs() Need {
+	return NeedWireless
+}
+
+func (s *NetworkInfoScreen) Animating() bool {
+	return s.animating
+}
+
+func (s *MenuScreen) Animating() bool {
+	return s.list.Animating()
 }
 
 func (s *SystemInfoScreen) HandleKey(key byte) bool {
@@ -305,200 +359,73 @@ func (s *SystemInfoScreen) HandleKey(key byte) bool {
 }
 
 func (s *AboutScreen) HandleKey(key byte) bool {
-	// No custom key handling (handled globally for ESC)
+	// No custom key handling (ESC pops it)
 	return false
 }
 
 func (s *MenuScreen) HandleKey(key byte) bool {
-	changed := false
-	if globalInDialog != nil && atomic.LoadInt32(globalInDialog) == 1 {
-		switch key {
-		case KEY_ENTER:
-			if globalDialogResult != nil && globalInDialog != nil {
-				atomic.StoreInt32(globalDialogResult, 1)
-				atomic.StoreInt32(globalInDialog, 0)
-				changed = true
-			}
-		case KEY_ESC:
-			if globalDialogResult != nil && globalInDialog != nil {
-				atomic.StoreInt32(globalDialogResult, 2)
-				atomic.StoreInt32(globalInDialog, 0)
-				changed = true
-			}
-		}
-		return changed
+	if s.list.HandleKey(key) {
+		return true
 	}
+	changed := false
 	switch key {
-	case KEY_UP:
-		if globalMenuIndex != nil && atomic.LoadInt32(globalMenuIndex) > 0 {
-			atomic.AddInt32(globalMenuIndex, -1)
-			changed = true
-		}
-	case KEY_DOWN:
-		if globalMenuIndex != nil && atomic.LoadInt32(globalMenuIndex) < 1 {
-			atomic.AddInt32(globalMenuIndex, 1)
-			changed = true
-		}
 	case KEY_ENTER:
-		if globalInDialog != nil && globalMenuIndex != nil && globalDialogType != nil {
-			atomic.StoreInt32(globalInDialog, 1)
-			if atomic.LoadInt32(globalMenuIndex) == 0 {
-				atomic.StoreInt32(globalDialogType, 1)
-			} else if atomic.LoadInt32(globalMenuIndex) == 1 {
-				atomic.StoreInt32(globalDialogType, 2)
-			}
-			changed = true
+		item, _ := s.list.SelectedItem()
+		switch item.Label {
+		case "Services":
+			s.nav.Push(s.services)
+		case "Processes":
+			s.nav.Push(s.processes)
+		case "Shutdown":
+			s.nav.Push(NewConfirmDialog(s.nav, "Shut down the system?", panelLock.Guard(s.nav, func() {
+				go execCommand("shutdown", "-h", "now")
+				s.nav.Home()
+			})))
+		case "Reboot":
+			s.nav.Push(NewConfirmDialog(s.nav, "Reboot the system?", panelLock.Guard(s.nav, func() {
+				go execCommand("reboot")
+				s.nav.Home()
+			})))
 		}
+		changed = true
 	case KEY_ESC:
-		// Exit menu, go back to main screen
-		atomic.StoreInt32(globalMenuIndex, 0)
-		atomic.StoreInt32(globalInDialog, 0)
-		atomic.StoreInt32(globalDialogType, 0)
-		atomic.StoreInt32(globalDialogResult, 0)
-		if globalRequestedScreen != nil {
-			atomic.StoreInt32(globalRequestedScreen, 0)
-		}
+		// Leave the menu, starting from the top next time
+		s.list.Select(0)
+		s.nav.Pop()
 		changed = true
 	}
 	return changed
 }
 
 func (s *NetworkInfoScreen) HandleKey(key byte) bool {
-	changed := false
-	if globalNetIfIndex == nil {
-		return false
-	}
-	ifaces, _ := GetNetworkInterfaces()
+	ifaces := s.interfaces()
 	if len(ifaces) == 0 {
 		return false
 	}
 	switch key {
 	case KEY_UP:
-		if *globalNetIfIndex > 0 {
-			atomic.AddInt32(globalNetIfIndex, -1)
+		if s.index > 0 {
+			s.index--
 		} else {
-			atomic.StoreInt32(globalNetIfIndex, int32(len(ifaces)-1))
+			s.index = len(ifaces) - 1
 		}
-		changed = true
+		s.page = 0
 	case KEY_DOWN:
-		if int(*globalNetIfIndex) < len(ifaces)-1 {
-			atomic.AddInt32(globalNetIfIndex, 1)
+		if s.index < len(ifaces)-1 {
+			s.index++
 		} else {
-			atomic.StoreInt32(globalNetIfIndex, 0)
-		}
-		changed = true
-	}
-	return changed
-}
-
-func (s *ServiceManagerScreen) HandleKey(key byte) bool {
-	changed := false
-	services := GetRunningServices()
-	numServices := len(services)
-
-	if globalServiceIndex == nil || globalServiceViewOffset == nil || globalServiceAction == nil {
-		return false
-	}
-
-	selectedIndexForDialog := int(atomic.LoadInt32(globalServiceIndex))
-
-	if numServices == 0 && atomic.LoadInt32(globalServiceAction) == 0 {
-		return false
-	}
-
-	const maxItemsOnScreen = 3
-
-	if atomic.LoadInt32(globalServiceAction) != 0 {
-		switch key {
-		case KEY_ENTER:
-			if selectedIndexForDialog >= 0 && selectedIndexForDialog < numServices {
-				actionType := atomic.LoadInt32(globalServiceAction)
-				serviceToActOn := services[selectedIndexForDialog]
-				if actionType == 1 { // Stop
-					go ServiceAction(serviceToActOn, "stop")
-				} else if actionType == 2 { // Restart
-					go ServiceAction(serviceToActOn, "restart")
-				}
-			}
-			atomic.StoreInt32(globalServiceAction, 0)
-			changed = true
-		case KEY_ESC:
-			atomic.StoreInt32(globalServiceAction, 0)
-			changed = true
-		}
-		return changed
-	}
-
-	currentSelectedIndex := int(atomic.LoadInt32(globalServiceIndex))
-	currentViewOffset := int(atomic.LoadInt32(globalServiceViewOffset))
-
-	switch key {
-	case KEY_UP:
-		if currentSelectedIndex > 0 {
-			newSelectedIndex := currentSelectedIndex - 1
-			atomic.StoreInt32(globalServiceIndex, int32(newSelectedIndex))
-			if newSelectedIndex < currentViewOffset {
-				atomic.StoreInt32(globalServiceViewOffset, int32(newSelectedIndex))
-			}
-			changed = true
-		}
-	case KEY_DOWN:
-		if currentSelectedIndex < numServices-1 {
-			newSelectedIndex := currentSelectedIndex + 1
-			atomic.StoreInt32(globalServiceIndex, int32(newSelectedIndex))
-			if newSelectedIndex >= currentViewOffset+maxItemsOnScreen {
-				atomic.StoreInt32(globalServiceViewOffset, int32(newSelectedIndex-maxItemsOnScreen+1))
-			}
-			changed = true
-		}
-	case KEY_LEFT: // Trigger Stop action
-		if numServices > 0 && currentSelectedIndex >= 0 && currentSelectedIndex < numServices {
-			atomic.StoreInt32(globalServiceAction, 1) // 1 for Stop
-			changed = true
-		}
-	case KEY_RIGHT: // Trigger Restart action
-		if numServices > 0 && currentSelectedIndex >= 0 && currentSelectedIndex < numServices {
-			atomic.StoreInt32(globalServiceAction, 2) // 2 for Restart
-			changed = true
+			s.index = 0
 		}
-	}
-
-	// Final clamping and validation after potential changes
-	if numServices == 0 {
-		atomic.StoreInt32(globalServiceIndex, 0)
-		atomic.StoreInt32(globalServiceViewOffset, 0)
-	} else {
-		// Clamp selectedIndex
-		finalSelectedIndex := int(atomic.LoadInt32(globalServiceIndex))
-		if finalSelectedIndex >= numServices {
-			finalSelectedIndex = numServices - 1
-		}
-		if finalSelectedIndex < 0 {
-			finalSelectedIndex = 0
-		}
-		atomic.StoreInt32(globalServiceIndex, int32(finalSelectedIndex))
-
-		// Clamp viewOffset, ensuring selected item is visible
-		finalViewOffset := int(atomic.LoadInt32(globalServiceViewOffset)) // Re-read
-		if numServices <= maxItemsOnScreen {
-			finalViewOffset = 0
+		s.page = 0
+	case KEY_ENTER:
+		if s.page == len(netPages(ifaces[s.index], s.index, len(ifaces)))-1 {
+			s.nav.Push(NewInterfaceScreen(s.nav, ifaces[s.index].Name))
+			s.page = 0
 		} else {
-			maxPossibleViewOffset := numServices - maxItemsOnScreen
-			// Ensure selected item is visible
-			if finalSelectedIndex < finalViewOffset {
-				finalViewOffset = finalSelectedIndex
-			} else if finalSelectedIndex >= finalViewOffset+maxItemsOnScreen {
-				finalViewOffset = finalSelectedIndex - maxItemsOnScreen + 1
-			}
-			// Clamp viewOffset itself
-			if finalViewOffset < 0 {
-				finalViewOffset = 0
-			}
-			if finalViewOffset > maxPossibleViewOffset {
-				finalViewOffset = maxPossibleViewOffset
-			}
+			s.page++
 		}
-		atomic.StoreInt32(globalServiceViewOffset, int32(finalViewOffset))
+	default:
+		return false
 	}
-	return changed
+	return true
 }
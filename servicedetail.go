@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+)
+
+// journalLines is how many journal entries the detail screen shows, and
+// serviceDetailRefresh how often it asks for them and the details again.
+const (
+	journalLines         = 20
+	serviceDetailRefresh = 2 * time.Second
+)
+
+// ServiceDetailScreen shows one service: its description, state, what the
+// panel may do to it, main PID, how long it has been active, its memory and
+// CPU usage, and the last journal entries, newest first. UP/DOWN scroll;
+// the selected line scrolls sideways when it doesn't fit.
+type ServiceDetailScreen struct {
+	nav  *Navigator
+	name string
+	face font.Face
+	list *ListView
+
+	// Loading happens off the UI goroutine; these are only touched under
+	// nav.Do.
+	loading bool
+	loaded  time.Time
+}
+
+func NewServiceDetailScreen(nav *Navigator, name string) *ServiceDetailScreen {
+	s := &ServiceDetailScreen{nav: nav, name: name, face: defaultFace("5x7")}
+	lineHeight := s.face.Metrics().Height.Ceil() + 1
+	s.list = NewListView(image.Rect(0, 0, 128, 64), lineHeight)
+	s.list.Face = s.face
+	s.list.SetItems([]ListItem{{Label: name}, {Label: "Loading..."}})
+	return s
+}
+
+func (s *ServiceDetailScreen) Draw(fb *image.Gray) {
+	if !s.loading && time.Since(s.loaded) >= serviceDetailRefresh {
+		s.loading = true
+		go s.load()
+	}
+	s.list.Draw(fb)
+}
+
+// load fetches the details and the journal and shows them.
+func (s *ServiceDetailScreen) load() {
+	details, err := systemd.Details(s.name)
+	journal, jerr := GetJournal(s.name, journalLines)
+	if jerr != nil {
+		log.Printf("Journal of %s: %v", s.name, jerr)
+	}
+	s.nav.Do(func() {
+		s.loading = false
+		s.loaded = time.Now()
+		items := []ListItem{{Label: s.name}}
+		if err != nil {
+			items = append(items, ListItem{Label: err.Error()})
+			s.list.SetItems(items)
+			return
+		}
+		items = append(items,
+			ListItem{Label: details.Description},
+			ListItem{Label: fmt.Sprintf("%s (%s)", details.ActiveState, details.SubState)},
+			ListItem{Label: allowedActions(s.name)},
+		)
+		if details.MainPID != 0 {
+			items = append(items, ListItem{Label: fmt.Sprintf("PID %d", details.MainPID)})
+		}
+		if !details.ActiveSince.IsZero() {
+			items = append(items, ListItem{Label: "Up " + formatDuration(time.Since(details.ActiveSince))})
+		}
+		mem, cpu := "n/a", "n/a"
+		if details.Memory >= 0 {
+			mem = formatBytes(uint64(details.Memory))
+		}
+		if details.CPU >= 0 {
+			cpu = formatCPUTime(details.CPU)
+		}
+		items = append(items, ListItem{Label: fmt.Sprintf("Mem %s CPU %s", mem, cpu)})
+		switch {
+		case jerr != nil:
+			items = append(items, ListItem{Label: "No journal"})
+		case len(journal) == 0:
+			items = append(items, ListItem{Label: "Journal empty"})
+		default:
+			items = append(items, ListItem{Label: "Journal:"})
+			for _, e := range journal {
+				items = append(items, ListItem{Label: e.Time.Format("15:04") + " " + e.Message})
+			}
+		}
+		s.list.SetItems(items)
+	})
+}
+
+// allowedActions says what the policy lets the panel do to a service.
+func allowedActions(name string) string {
+	policy := systemd.Policy()
+	var allowed []string
+	for _, action := range []string{"start", "stop", "restart"} {
+		if policy.Allows(name, action) {
+			allowed = append(allowed, action)
+		}
+	}
+	if len(allowed) == 0 {
+		return "Read-only"
+	}
+	return "Can " + strings.Join(allowed, ", ")
+}
+
+// formatCPUTime formats CPU time to a tenth of a second under a minute and
+// like formatDuration above.
+func formatCPUTime(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return formatDuration(d)
+}
+
+func (s *ServiceDetailScreen) Animating() bool {
+	return s.list.Animating()
+}
+
+func (s *ServiceDetailScreen) HandleKey(key byte) bool {
+	return s.list.HandleKey(key)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// serviceFilter picks which services the service manager lists.
+type serviceFilter int
+
+const (
+	filterRunning serviceFilter = iota
+	filterFailed
+	filterAll
+	filterPinned
+	numFilters
+)
+
+var filterNames = [numFilters]string{"Running", "Failed", "All", "Pinned"}
+
+// ServiceManagerScreen lists services, kept current by the systemd client:
+// the running ones, the failed ones, all of them or the pinned ones from
+// the config. HELP cycles through those. UP/DOWN scroll, ENTER opens the
+// selected service's details, and LEFT stops it and RIGHT restarts it, or
+// starts it if it isn't active, after a confirmation and if the policy in
+// the config allows it. The bottom row shows the filter, or how the last
+// job went.
+type ServiceManagerScreen struct {
+	nav       *Navigator
+	list      *ListView
+	pinned    []string
+	filter    serviceFilter
+	units     []Unit // what the list shows, in the same order
+	status    string // progress or outcome of the last action
+	marquee   Marquee
+	animating bool
+}
+
+func NewServiceManagerScreen(nav *Navigator, cfg ServicesConfig) *ServiceManagerScreen {
+	return &ServiceManagerScreen{
+		nav:    nav,
+		list:   NewListView(image.Rect(0, 0, 128, 48), 16),
+		pinned: cfg.Pinned,
+	}
+}
+
+func (s *ServiceManagerScreen) Animating() bool {
+	return s.list.Animating() || s.animating
+}
+
+func (s *ServiceManagerScreen) Draw(fb *image.Gray) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{
+		Dst:  fb,
+		Src:  image.Black,
+		Face: face,
+	}
+	err := s.refresh()
+	switch {
+	case s.list.Len() > 0:
+		s.list.Draw(fb)
+	case err != nil:
+		for i, line := range WrapText(face, "systemd: "+err.Error(), fb.Bounds().Dx()) {
+			if i == 3 {
+				break
+			}
+			d.Dot = fixed.P(0, Baseline(face, 16*i, 16))
+			d.DrawString(line)
+		}
+	default:
+		d.Dot = fixed.P(0, 16)
+		d.DrawString("No services found")
+	}
+	status := s.status
+	if status == "" {
+		status = fmt.Sprintf("%s (%d)", filterNames[s.filter], len(s.units))
+	}
+	s.animating = s.marquee.Draw(d, image.Rect(0, 48, fb.Bounds().Dx(), 64), Baseline(face, 48, 16), status)
+}
+
+// refresh reloads the list of services that pass the filter.
+func (s *ServiceManagerScreen) refresh() error {
+	all, err := systemd.Units()
+	var units []Unit
+	switch s.filter {
+	case filterPinned:
+		byName := make(map[string]Unit, len(all))
+		for _, u := range all {
+			byName[u.Name] = u
+		}
+		policy := systemd.Policy()
+		for _, name := range s.pinned {
+			if !policy.Visible(name) {
+				continue
+			}
+			u, ok := byName[name]
+			if !ok {
+				u = Unit{Name: name, LoadState: "not-found", ActiveState: "inactive", SubState: "dead"}
+			}
+			units = append(units, u)
+		}
+	default:
+		for _, u := range all {
+			if s.filter == filterAll ||
+				s.filter == filterRunning && u.Running() ||
+				s.filter == filterFailed && u.Failed() {
+				units = append(units, u)
+			}
+		}
+	}
+	items := make([]ListItem, len(units))
+	for i, u := range units {
+		icon := &IconStopped
+		switch {
+		case u.Failed():
+			icon = &IconFailed
+		case u.Active():
+			icon = &IconRunning
+		}
+		items[i] = ListItem{Label: u.Name, Icon: icon}
+	}
+	s.units = units
+	s.list.SetItems(items)
+	return err
+}
+
+func (s *ServiceManagerScreen) HandleKey(key byte) bool {
+	if key == KEY_HELP {
+		s.filter = (s.filter + 1) % numFilters
+		s.setStatus("")
+		s.refresh()
+		s.list.Select(0)
+		return true
+	}
+	s.refresh()
+	if s.list.HandleKey(key) {
+		return true
+	}
+	i := s.list.Selected()
+	if i < 0 {
+		return false
+	}
+	unit := s.units[i]
+	switch key {
+	case KEY_ENTER:
+		s.nav.Push(NewServiceDetailScreen(s.nav, unit.Name))
+		return true
+	case KEY_LEFT: // Trigger Stop action
+		if unit.Active() {
+			s.confirmAction(unit.Name, "stop")
+		}
+		return true
+	case KEY_RIGHT: // Trigger Restart, or Start if it isn't active
+		if unit.Active() {
+			s.confirmAction(unit.Name, "restart")
+		} else {
+			s.confirmAction(unit.Name, "start")
+		}
+		return true
+	}
+	return false
+}
+
+// confirmAction asks before running a systemd job on a service, and shows
+// its result when it's done. Jobs the policy doesn't allow aren't offered.
+func (s *ServiceManagerScreen) confirmAction(service, action string) {
+	verb := strings.ToUpper(action[:1]) + action[1:]
+	if !systemd.Policy().Allows(service, action) {
+		s.setStatus(fmt.Sprintf("%s %s: not allowed", verb, service))
+		return
+	}
+	s.nav.Push(NewConfirmDialog(s.nav, fmt.Sprintf("%s %s?", verb, service), panelLock.Guard(s.nav, func() {
+		s.setStatus(fmt.Sprintf("%s %s...", verb, service))
+		go func() {
+			err := ServiceAction(service, action)
+			s.nav.Do(func() {
+				if err != nil {
+					log.Printf("%s %s: %v", action, service, err)
+					s.setStatus(err.Error())
+				} else {
+					s.setStatus(fmt.Sprintf("%s %s: done", verb, service))
+				}
+			})
+		}()
+	})))
+}
+
+func (s *ServiceManagerScreen) setStatus(status string) {
+	s.status = status
+	s.marquee.Reset()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Simulator is a hardware-free Backend. Frames are either drawn in the
+// terminal or written out as PNG snapshots, and key presses are read from
+// the keyboard:
+//
+//	arrow keys       KEY_UP/KEY_DOWN/KEY_LEFT/KEY_RIGHT
+//	Enter            KEY_ENTER
+//	Esc, Backspace   KEY_ESC
+//	h, ?             KEY_HELP
+type Simulator struct {
+	in      *os.File
+	keys    chan byte
+	render  func(fb *image.Gray) error
+	cleanup func()
+	oldTerm *unix.Termios
+}
+
+// NewTerminalSimulator renders frames to out using Unicode half-block
+// characters, two pixel rows per text line.
+func NewTerminalSimulator(in *os.File, out io.Writer) *Simulator {
+	s := &Simulator{in: in, keys: make(chan byte, 16)}
+	w := bufio.NewWriter(out)
+	s.render = func(fb *image.Gray) error {
+		renderHalfBlocks(w, fb)
+		return w.Flush()
+	}
+	s.cleanup = func() {
+		fmt.Fprint(w, "\x1b[?25h\r\n")
+		w.Flush()
+	}
+	fmt.Fprint(w, "\x1b[2J\x1b[?25l")
+	return s
+}
+
+// NewPNGSimulator writes every distinct frame to dir as frame-NNNNNN.png and
+// keeps dir/current.png pointing at the latest one.
+func NewPNGSimulator(in *os.File, dir string, scale int) *Simulator {
+	if scale < 1 {
+		scale = 1
+	}
+	s := &Simulator{in: in, keys: make(chan byte, 16)}
+	var last []uint8
+	seq := 0
+	s.render = func(fb *image.Gray) error {
+		if last != nil && bytes.Equal(last, fb.Pix) {
+			return nil
+		}
+		last = append(last[:0], fb.Pix...)
+		seq++
+		img := scaleGray(fb, scale)
+		if err := writePNG(filepath.Join(dir, fmt.Sprintf("frame-%06d.png", seq)), img); err != nil {
+			return err
+		}
+		return writePNG(filepath.Join(dir, "current.png"), img)
+	}
+	return s
+}
+
+func (s *Simulator) Init() error {
+	if t, err := unix.IoctlGetTermios(int(s.in.Fd()), unix.TCGETS); err == nil {
+		raw := *t
+		raw.Lflag &^= unix.ICANON | unix.ECHO
+		raw.Cc[unix.VMIN] = 1
+		raw.Cc[unix.VTIME] = 0
+		if err := unix.IoctlSetTermios(int(s.in.Fd()), unix.TCSETS, &raw); err != nil {
+			return fmt.Errorf("cannot put terminal in raw mode: %w", err)
+		}
+		s.oldTerm = t
+	}
+	go s.readKeys()
+	return nil
+}
+
+func (s *Simulator) WriteFrame(fb *image.Gray) error {
+	return s.render(fb)
+}
+
+func (s *Simulator) ReadKey(timeout time.Duration) (key byte, ok bool, err error) {
+	select {
+	case key := <-s.keys:
+		return key, true, nil
+	case <-time.After(timeout):
+		return 0, false, nil
+	}
+}
+
+func (s *Simulator) Close() error {
+	if s.cleanup != nil {
+		s.cleanup()
+	}
+	if s.oldTerm != nil {
+		return unix.IoctlSetTermios(int(s.in.Fd()), unix.TCSETS, s.oldTerm)
+	}
+	return nil
+}
+
+func (s *Simulator) readKeys() {
+	buf := make([]byte, 32)
+	for {
+		n, err := s.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			s.keys <- key
+		}
+	}
+}
+
+// decodeKeys translates a chunk of terminal input into panel key codes.
+// Terminals deliver an escape sequence in a single read, so a lone ESC byte
+// at the end of a chunk is the Esc key itself.
+func decodeKeys(in []byte) []byte {
+	var keys []byte
+	for i := 0; i < len(in); i++ {
+		switch in[i] {
+		case 0x1b:
+			if i+2 < len(in) && (in[i+1] == '[' || in[i+1] == 'O') {
+				switch in[i+2] {
+				case 'A':
+					keys = append(keys, KEY_UP)
+				case 'B':
+					keys = append(keys, KEY_DOWN)
+				case 'C':
+					keys = append(keys, KEY_RIGHT)
+				case 'D':
+					keys = append(keys, KEY_LEFT)
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, KEY_ESC)
+		case '\r', '\n':
+			keys = append(keys, KEY_ENTER)
+		case 0x7f, 0x08:
+			keys = append(keys, KEY_ESC)
+		case 'h', '?':
+			keys = append(keys, KEY_HELP)
+		}
+	}
+	return keys
+}
+
+func renderHalfBlocks(w io.Writer, fb *image.Gray) {
+	b := fb.Bounds()
+	lit := func(x, y int) bool {
+		return y < b.Max.Y && fb.GrayAt(x, y).Y < 128
+	}
+	var sb strings.Builder
+	sb.WriteString("\x1b[H┌" + strings.Repeat("─", b.Dx()) + "┐\r\n")
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		sb.WriteString("│")
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top, bottom := lit(x, y), lit(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("│\r\n")
+	}
+	sb.WriteString("└" + strings.Repeat("─", b.Dx()) + "┘\r\n")
+	sb.WriteString("arrows: navigate  enter: OK  esc/backspace: ESC  h: HELP  ctrl-c: quit\r\n")
+	io.WriteString(w, sb.String())
+}
+
+func scaleGray(fb *image.Gray, scale int) *image.Gray {
+	b := fb.Bounds()
+	out := image.NewGray(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			out.SetGray(x, y, color.Gray{Y: fb.GrayAt(b.Min.X+x/scale, b.Min.Y+y/scale).Y})
+		}
+	}
+	return out
+}
+
+func writePNG(path string, img image.Image) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
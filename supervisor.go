@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+
+	"lcdinator/panel"
+)
+
+const (
+	reconnectMinBackoff = 500 * time.Millisecond
+	reconnectMaxBackoff = 30 * time.Second
+	// A link that has worked for this long starts over from the shortest
+	// backoff when it fails; one that fails sooner backs off further.
+	reconnectHealthy = time.Minute
+)
+
+// errPanelOffline is returned by WriteFrame while the panel is
+// disconnected and the next reconnect attempt isn't due yet.
+var errPanelOffline = errors.New("LCD offline")
+
+// SupervisedPanel wraps a serial panel so that I/O errors don't take the
+// daemon down. When the port can't be opened, or a write or a key read
+// fails, the panel is closed and WriteFrame tries to open it again, at most
+// once per call and with exponential backoff between attempts, also when
+// the panel opens fine but keeps failing soon after. Nothing
+// blocks for longer than one attempt, so the main loop keeps running and
+// can still shut down while the panel is unplugged.
+type SupervisedPanel struct {
+	device    string
+	mode      *serial.Mode
+	initDelay time.Duration
+
+	mu        sync.Mutex
+	lcd       *panel.Panel // nil while disconnected
+	connected time.Time    // when lcd was opened
+	backoff   time.Duration
+	retry     time.Time // no reconnect attempt before this
+}
+
+func NewSupervisedPanel(device string, mode *serial.Mode, initDelay time.Duration) *SupervisedPanel {
+	return &SupervisedPanel{device: device, mode: mode, initDelay: initDelay}
+}
+
+// Init makes the first connection attempt. A panel that isn't there yet is
+// not an error; WriteFrame keeps trying.
+func (s *SupervisedPanel) Init() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.connect(); err != nil {
+		log.Printf("LCD not available: %v", err)
+	}
+	return nil
+}
+
+func (s *SupervisedPanel) WriteFrame(fb *image.Gray) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lcd == nil {
+		if time.Now().Before(s.retry) {
+			return errPanelOffline
+		}
+		if err := s.connect(); err != nil {
+			return err
+		}
+		log.Printf("LCD connected")
+	}
+	if err := s.lcd.WriteFrame(fb); err != nil {
+		s.disconnect()
+		return err
+	}
+	return nil
+}
+
+func (s *SupervisedPanel) ReadKey(timeout time.Duration) (key byte, ok bool, err error) {
+	s.mu.Lock()
+	lcd := s.lcd
+	s.mu.Unlock()
+	if lcd == nil {
+		time.Sleep(timeout)
+		return 0, false, nil
+	}
+	key, ok, err = lcd.ReadKey(timeout)
+	if err != nil {
+		log.Printf("LCD read failed: %v", err)
+		s.mu.Lock()
+		// Unless a write has already replaced the panel.
+		if s.lcd == lcd {
+			s.disconnect()
+		}
+		s.mu.Unlock()
+		return 0, false, nil
+	}
+	return key, ok, nil
+}
+
+func (s *SupervisedPanel) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lcd == nil {
+		return nil
+	}
+	return s.lcd.Close()
+}
+
+// connect opens and initializes the panel. If that fails, the next attempt
+// is put off. s.mu must be held.
+func (s *SupervisedPanel) connect() error {
+	lcd, err := panel.Open(s.device, s.mode)
+	if err == nil {
+		lcd.SetInitDelay(s.initDelay)
+		if err = lcd.Init(); err != nil {
+			lcd.Close()
+		}
+	}
+	if err != nil {
+		s.postpone()
+		return fmt.Errorf("%w (retrying in %v)", err, s.backoff)
+	}
+	s.lcd = lcd
+	s.connected = time.Now()
+	return nil
+}
+
+// disconnect closes a panel that has failed. If it had been working for
+// long enough, the next WriteFrame tries to reconnect straight away,
+// otherwise the attempt is put off. s.mu must be held.
+func (s *SupervisedPanel) disconnect() {
+	s.lcd.Close()
+	s.lcd = nil
+	if time.Since(s.connected) >= reconnectHealthy {
+		s.backoff = 0
+		s.retry = time.Time{}
+	} else {
+		s.postpone()
+	}
+}
+
+// postpone puts the next reconnect attempt off by a backoff that doubles
+// every time. s.mu must be held.
+func (s *SupervisedPanel) postpone() {
+	s.backoff *= 2
+	if s.backoff < reconnectMinBackoff {
+		s.backoff = reconnectMinBackoff
+	}
+	if s.backoff > reconnectMaxBackoff {
+		s.backoff = reconnectMaxBackoff
+	}
+	s.retry = time.Now().Add(s.backoff)
+}
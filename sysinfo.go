@@ -4,79 +4,359 @@ import (
 	"fmt"
 	gonet "net"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	psnet "github.com/shirou/gopsutil/v3/net"
+	"golang.org/x/sys/unix"
 )
 
-func GetCPUUsage() float64 {
-	f, err := os.Open("/proc/stat")
-	if err != nil {
+// CPUTimes are the jiffies a CPU has spent in total and idle (including
+// iowait) since boot.
+type CPUTimes struct {
+	Total, Idle uint64
+}
+
+// Usage returns the percentage of time busy between prev and t.
+func (t CPUTimes) Usage(prev CPUTimes) float64 {
+	if t.Total <= prev.Total || t.Idle < prev.Idle {
 		return 0
 	}
+	return 100 * (1 - float64(t.Idle-prev.Idle)/float64(t.Total-prev.Total))
+}
+
+// GetCPUTimes reads the aggregate "cpu" line and the per-core "cpuN" lines
+// of /proc/stat.
+func GetCPUTimes() (all CPUTimes, cores []CPUTimes, ok bool) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return CPUTimes{}, nil, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		var v [8]uint64
+		for i := range v {
+			v[i], _ = strconv.ParseUint(fields[i+1], 10, 64)
+		}
+		// user nice system idle iowait irq softirq steal
+		t := CPUTimes{Total: v[0] + v[1] + v[2] + v[3] + v[4] + v[5] + v[6] + v[7], Idle: v[3] + v[4]}
+		if fields[0] == "cpu" {
+			all, ok = t, true
+		} else {
+			cores = append(cores, t)
+		}
+	}
+	return all, cores, ok
+}
+
+// GetLoadAvg returns the 1, 5 and 15 minute load averages.
+func GetLoadAvg() (load [3]float64) {
+	f, err := os.Open("/proc/loadavg")
+	if err != nil {
+		return load
+	}
 	defer f.Close()
-	var user1, nice1, system1, idle1, iowait1, irq1, softirq1, steal1 uint64
-	fmt.Fscanf(f, "cpu  %d %d %d %d %d %d %d %d", &user1, &nice1, &system1, &idle1, &iowait1, &irq1, &softirq1, &steal1)
-	total1 := user1 + nice1 + system1 + idle1 + iowait1 + irq1 + softirq1 + steal1
-	idleAll1 := idle1 + iowait1
-	time.Sleep(100 * time.Millisecond)
-	f.Seek(0, 0)
-	fmt.Fscanf(f, "cpu  %d %d %d %d %d %d %d %d", &user1, &nice1, &system1, &idle1, &iowait1, &irq1, &softirq1, &steal1)
-	total2 := user1 + nice1 + system1 + idle1 + iowait1 + irq1 + softirq1 + steal1
-	idleAll2 := idle1 + iowait1
-	deltaTotal := float64(total2 - total1)
-	deltaIdle := float64(idleAll2 - idleAll1)
-	if deltaTotal == 0 {
+	fmt.Fscanf(f, "%f %f %f", &load[0], &load[1], &load[2])
+	return load
+}
+
+type Temperature struct {
+	Name    string // thermal zone type or hwmon chip name and label
+	Celsius float64
+}
+
+// GetTemperatures reads the thermal zones in /sys/class/thermal and the
+// sensors in /sys/class/hwmon. Both report millidegrees Celsius.
+func GetTemperatures() []Temperature {
+	var temps []Temperature
+	readMilli := func(path string) (float64, bool) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, false
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return 0, false
+		}
+		return float64(v) / 1000, true
+	}
+	readName := func(path string) string {
+		data, _ := os.ReadFile(path)
+		return strings.TrimSpace(string(data))
+	}
+
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	for _, zone := range zones {
+		if c, ok := readMilli(filepath.Join(zone, "temp")); ok {
+			temps = append(temps, Temperature{Name: readName(filepath.Join(zone, "type")), Celsius: c})
+		}
+	}
+
+	chips, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+	for _, chip := range chips {
+		name := readName(filepath.Join(chip, "name"))
+		inputs, _ := filepath.Glob(filepath.Join(chip, "temp*_input"))
+		for _, input := range inputs {
+			c, ok := readMilli(input)
+			if !ok {
+				continue
+			}
+			sensor := name
+			if label := readName(strings.TrimSuffix(input, "_input") + "_label"); label != "" {
+				sensor += " " + label
+			}
+			temps = append(temps, Temperature{Name: sensor, Celsius: c})
+		}
+	}
+	return temps
+}
+
+// MemInfo is the part of /proc/meminfo the screens show, in bytes.
+type MemInfo struct {
+	Total, Free, Available uint64
+	Buffers, Cached, Shmem uint64
+	SwapTotal, SwapFree    uint64
+}
+
+// Used is memory that can't be reclaimed without swapping: everything but
+// MemAvailable, which counts free memory plus the reclaimable part of the
+// page cache and slab.
+func (m MemInfo) Used() uint64 {
+	if m.Available > m.Total {
 		return 0
 	}
-	return 100.0 * (1.0 - deltaIdle/deltaTotal)
+	return m.Total - m.Available
 }
 
-func GetMemInfo() (used, total int) {
-	f, err := os.Open("/proc/meminfo")
+func (m MemInfo) SwapUsed() uint64 {
+	if m.SwapFree > m.SwapTotal {
+		return 0
+	}
+	return m.SwapTotal - m.SwapFree
+}
+
+func GetMemInfo() MemInfo {
+	var m MemInfo
+	data, err := os.ReadFile("/proc/meminfo")
 	if err != nil {
-		return 0, 0
+		return m
 	}
-	defer f.Close()
-	var memTotal, memFree, buffers, cached int
-	scan := func() {
-		var label string
-		var value int
-		for {
-			_, err := fmt.Fscanf(f, "%s %d kB\n", &label, &value)
-			if err != nil {
-				break
+	fields := map[string]*uint64{
+		"MemTotal:":     &m.Total,
+		"MemFree:":      &m.Free,
+		"MemAvailable:": &m.Available,
+		"Buffers:":      &m.Buffers,
+		"Cached:":       &m.Cached,
+		"Shmem:":        &m.Shmem,
+		"SwapTotal:":    &m.SwapTotal,
+		"SwapFree:":     &m.SwapFree,
+	}
+	hasAvailable := false
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 || fields[f[0]] == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		*fields[f[0]] = v * 1024
+		hasAvailable = hasAvailable || f[0] == "MemAvailable:"
+	}
+	// Kernels before 3.14 don't have MemAvailable; estimate it the old way.
+	// Shmem lives in the page cache but can't be dropped from it.
+	if !hasAvailable {
+		m.Available = m.Free + m.Buffers + m.Cached
+		if m.Cached >= m.Shmem {
+			m.Available -= m.Shmem
+		}
+	}
+	return m
+}
+
+// ProcessMem is a process and its resident set size in bytes.
+type ProcessMem struct {
+	PID  int
+	Name string
+	RSS  uint64
+}
+
+// GetTopProcessesByRSS returns the n processes using the most memory,
+// largest first, from the Name and VmRSS lines of /proc/[pid]/status.
+// Kernel threads have no VmRSS and are left out.
+func GetTopProcessesByRSS(n int) []ProcessMem {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var procs []ProcessMem
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// The process may have exited since ReadDir.
+		data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "status"))
+		if err != nil {
+			continue
+		}
+		p := ProcessMem{PID: pid}
+		hasRSS := false
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
 			}
-			switch label {
-			case "MemTotal:":
-				memTotal = value
-			case "MemFree:":
-				memFree = value
-			case "Buffers:":
-				buffers = value
-			case "Cached:":
-				cached = value
+			switch key {
+			case "Name":
+				p.Name = strings.TrimSpace(value)
+			case "VmRSS":
+				f := strings.Fields(value)
+				if len(f) > 0 {
+					kb, _ := strconv.ParseUint(f[0], 10, 64)
+					p.RSS = kb * 1024
+					hasRSS = true
+				}
 			}
 		}
+		if hasRSS {
+			procs = append(procs, p)
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].RSS > procs[j].RSS })
+	if len(procs) > n {
+		procs = procs[:n]
 	}
-	scan()
-	total = memTotal / 1024
-	used = (memTotal - memFree - buffers - cached) / 1024
-	return
+	return procs
 }
 
-func GetDiskInfo() (used, total int) {
+// FSUsage is the space and inode usage of a filesystem. Used counts what
+// is not free, including blocks reserved for root, so Used+Avail can be
+// less than Total.
+type FSUsage struct {
+	Total, Used, Avail uint64 // bytes
+	Inodes, InodesUsed uint64
+}
+
+func GetFSUsage(path string) (FSUsage, error) {
 	var stat syscall.Statfs_t
-	err := syscall.Statfs("/", &stat)
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return FSUsage{}, err
+	}
+	bsize := uint64(stat.Bsize)
+	return FSUsage{
+		Total:      stat.Blocks * bsize,
+		Used:       (stat.Blocks - stat.Bfree) * bsize,
+		Avail:      stat.Bavail * bsize,
+		Inodes:     stat.Files,
+		InodesUsed: stat.Files - stat.Ffree,
+	}, nil
+}
+
+// Mount is a mounted filesystem backed by a block device.
+type Mount struct {
+	Device string
+	Path   string
+	FSType string
+	Dev    uint64 // device number of the filesystem, as in stat(2) st_dev
+}
+
+// flashFilesystems are mounted from a device that isn't a /dev path, like
+// "ubi0:rootfs", but are still real storage.
+var flashFilesystems = map[string]bool{"ubifs": true, "jffs2": true, "yaffs2": true}
+
+// GetMounts returns the real filesystems in /proc/self/mounts, leaving out
+// pseudo filesystems like proc and tmpfs. A path mounted over keeps only
+// its last mount.
+func GetMounts() []Mount {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	var mounts []Mount
+	index := make(map[string]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		m := Mount{Device: unescapeMount(fields[0]), Path: unescapeMount(fields[1]), FSType: fields[2]}
+		if !strings.HasPrefix(m.Device, "/dev/") && !flashFilesystems[m.FSType] {
+			continue
+		}
+		var st syscall.Stat_t
+		if err := syscall.Stat(m.Path, &st); err != nil {
+			continue
+		}
+		m.Dev = uint64(st.Dev)
+		if i, ok := index[m.Path]; ok {
+			mounts[i] = m
+			continue
+		}
+		index[m.Path] = len(mounts)
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes (\040 for a space) the kernel
+// uses for whitespace and backslashes in mount paths.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// DiskIO are the bytes read from and written to a block device since boot.
+type DiskIO struct {
+	ReadBytes, WriteBytes uint64
+}
+
+// GetDiskIO reads /proc/diskstats, keyed by device number so it can be
+// matched with Mount.Dev.
+func GetDiskIO() map[uint64]DiskIO {
+	data, err := os.ReadFile("/proc/diskstats")
 	if err != nil {
-		return 0, 0
+		return nil
 	}
-	total = int((stat.Blocks * uint64(stat.Bsize)) / (1024 * 1024 * 1024))
-	used = int(((stat.Blocks - stat.Bfree) * uint64(stat.Bsize)) / (1024 * 1024 * 1024))
-	return
+	stats := make(map[uint64]DiskIO)
+	for _, line := range strings.Split(string(data), "\n") {
+		// major minor name reads merged sectors ms writes merged sectors ...
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		major, err1 := strconv.ParseUint(fields[0], 10, 32)
+		minor, err2 := strconv.ParseUint(fields[1], 10, 32)
+		read, err3 := strconv.ParseUint(fields[5], 10, 64)
+		written, err4 := strconv.ParseUint(fields[9], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		// Sectors here are always 512 bytes, whatever the device uses.
+		stats[unix.Mkdev(uint32(major), uint32(minor))] = DiskIO{ReadBytes: read * 512, WriteBytes: written * 512}
+	}
+	return stats
 }
 
 func GetUptime() string {
@@ -87,9 +367,14 @@ func GetUptime() string {
 	defer f.Close()
 	var uptimeSeconds float64
 	fmt.Fscanf(f, "%f", &uptimeSeconds)
-	days := int(uptimeSeconds) / 86400
-	hours := (int(uptimeSeconds) % 86400) / 3600
-	minutes := (int(uptimeSeconds) % 3600) / 60
+	return formatDuration(time.Duration(uptimeSeconds * float64(time.Second)))
+}
+
+// formatDuration formats d to the minute, like "1d 02h 03m".
+func formatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
 	if days > 0 {
 		return fmt.Sprintf("%dd %02dh %02dm", days, hours, minutes)
 	}
@@ -100,95 +385,179 @@ func GetUptime() string {
 }
 
 type NetInterfaceInfo struct {
-	Name   string
-	IP     string
-	Up     bool
-	RxRate int64 // bytes/sec
-	TxRate int64 // bytes/sec
-	Signal int   // dBm, -1 if not wireless
+	Name                 string
+	Addrs                []string // all addresses in CIDR notation, IPv4 first
+	MAC                  string
+	MTU                  int
+	Up                   bool   // administratively up
+	Carrier              bool   // link detected
+	Speed                int    // Mb/s, -1 if unknown
+	Duplex               string // "full", "half" or "" if unknown
+	RxBytes              uint64 // counters since boot
+	TxBytes              uint64
+	RxErrors, TxErrors   uint64
+	RxDropped, TxDropped uint64
+	RxRate               int64         // bytes/sec, filled in by the Collector
+	TxRate               int64         // bytes/sec, filled in by the Collector
+	Wireless             *WirelessInfo // nil if not wireless
 }
 
-// For bandwidth calculation using gopsutil
-var prevIOCounters = make(map[string][2]uint64)
-var prevIOTimestamp = time.Now()
+// IPv4 returns the first IPv4 address without its prefix length, or "".
+func (n NetInterfaceInfo) IPv4() string {
+	for _, addr := range n.Addrs {
+		if ip, _, err := gonet.ParseCIDR(addr); err == nil && ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
 
-func GetNetworkInterfaces() ([]NetInterfaceInfo, error) {
-	var result []NetInterfaceInfo
+// GetNetworkInterfaces returns the interfaces with their addresses, link
+// details and counters, and with wireless details if asked for. Rates need
+// two readings, so they are left for the Collector to fill in.
+func GetNetworkInterfaces(wireless bool) ([]NetInterfaceInfo, error) {
 	ifaces, err := gonet.Interfaces()
 	if err != nil {
 		return nil, err
 	}
 	ioStats, _ := psnet.IOCounters(true)
-	now := time.Now()
-	dt := now.Sub(prevIOTimestamp).Seconds()
-	prevIOTimestamp = now
 	ioMap := make(map[string]psnet.IOCountersStat)
 	for _, stat := range ioStats {
 		ioMap[stat.Name] = stat
 	}
+	var wifi map[string]WirelessInfo
+	if wireless {
+		wifi = GetWirelessInfo()
+	}
+
+	var result []NetInterfaceInfo
 	for _, iface := range ifaces {
-		info := NetInterfaceInfo{Name: iface.Name, Up: iface.Flags&gonet.FlagUp != 0, RxRate: 0, TxRate: 0, Signal: -1}
+		info := NetInterfaceInfo{
+			Name:  iface.Name,
+			MAC:   iface.HardwareAddr.String(),
+			MTU:   iface.MTU,
+			Up:    iface.Flags&gonet.FlagUp != 0,
+			Speed: -1,
+		}
 		addrs, _ := iface.Addrs()
+		var v6 []string
 		for _, addr := range addrs {
-			if ipnet, ok := addr.(*gonet.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
-				info.IP = ipnet.IP.String()
+			if ipnet, ok := addr.(*gonet.IPNet); ok {
+				if ipnet.IP.To4() != nil {
+					info.Addrs = append(info.Addrs, ipnet.String())
+				} else {
+					v6 = append(v6, ipnet.String())
+				}
 			}
 		}
-		if stat, ok := ioMap[iface.Name]; ok && dt > 0 {
-			prev, ok := prevIOCounters[iface.Name]
-			if ok {
-				info.RxRate = int64(float64(stat.BytesRecv-prev[0]) / dt)
-				info.TxRate = int64(float64(stat.BytesSent-prev[1]) / dt)
+		info.Addrs = append(info.Addrs, v6...)
+
+		// These files can't be read while the interface is down, and speed
+		// is -1 for virtual ones.
+		sys := filepath.Join("/sys/class/net", iface.Name)
+		if data, err := os.ReadFile(filepath.Join(sys, "carrier")); err == nil {
+			info.Carrier = strings.TrimSpace(string(data)) == "1"
+		}
+		if data, err := os.ReadFile(filepath.Join(sys, "speed")); err == nil {
+			if speed, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && speed > 0 {
+				info.Speed = speed
 			}
-			prevIOCounters[iface.Name] = [2]uint64{stat.BytesRecv, stat.BytesSent}
-		}
-		// Signal strength (unchanged)
-		if _, err := os.Stat("/proc/net/wireless"); err == nil {
-			wf, _ := os.Open("/proc/net/wireless")
-			if wf != nil {
-				defer wf.Close()
-				var l string
-				for i := 0; i < 2; i++ {
-					fmt.Fscanln(wf, &l)
-				}
-				for {
-					_, err := fmt.Fscanln(wf, &l)
-					if err != nil {
-						break
-					}
-					var wname string
-					var sig int
-					fmt.Sscanf(l, "%s %*d. %d.", &wname, &sig)
-					wname = wname[:len(wname)-1]
-					if wname == iface.Name {
-						info.Signal = sig
-					}
-				}
+		}
+		if data, err := os.ReadFile(filepath.Join(sys, "duplex")); err == nil {
+			if d := strings.TrimSpace(string(data)); d == "full" || d == "half" {
+				info.Duplex = d
 			}
 		}
+
+		if stat, ok := ioMap[iface.Name]; ok {
+			info.RxBytes, info.TxBytes = stat.BytesRecv, stat.BytesSent
+			info.RxErrors, info.TxErrors = stat.Errin, stat.Errout
+			info.RxDropped, info.TxDropped = stat.Dropin, stat.Dropout
+		}
+		if w, ok := wifi[iface.Name]; ok {
+			info.Wireless = &w
+		}
 		result = append(result, info)
 	}
 	return result, nil
 }
 
-func GetRunningServices() []string {
-	cmd := exec.Command("systemctl", "list-units", "--type=service", "--state=running", "--no-legend", "--no-pager")
-	out, err := cmd.Output()
+// ProcStat is the part of /proc/[pid]/stat the process screen uses.
+type ProcStat struct {
+	PID       int
+	Name      string
+	State     byte
+	CPUTicks  uint64 // user + system time, in the same ticks as /proc/stat
+	Nice      int
+	StartTime uint64 // ticks after boot; tells a reused PID apart
+}
+
+// GetProcStats reads /proc/[pid]/stat of every process.
+func GetProcStats() []ProcStat {
+	entries, err := os.ReadDir("/proc")
 	if err != nil {
-		return []string{"systemctl error"}
+		return nil
 	}
-	lines := strings.Split(string(out), "\n")
-	var result []string
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) > 0 {
-			result = append(result, fields[0])
+	var procs []ProcStat
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if p, err := readProcStat(pid); err == nil {
+			procs = append(procs, p)
 		}
 	}
-	return result
+	return procs
 }
 
-func ServiceAction(service, action string) {
-	cmd := exec.Command("systemctl", action, service)
-	_ = cmd.Run()
+func readProcStat(pid int) (ProcStat, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ProcStat{}, err
+	}
+	// The name is in parentheses and may itself contain spaces and
+	// parentheses, so split around the last ")".
+	line := string(data)
+	open, end := strings.IndexByte(line, '('), strings.LastIndexByte(line, ')')
+	if open < 0 || end < open {
+		return ProcStat{}, fmt.Errorf("malformed stat for %d", pid)
+	}
+	// Fields after the name, starting with field 3 (state).
+	f := strings.Fields(line[end+1:])
+	if len(f) < 20 {
+		return ProcStat{}, fmt.Errorf("malformed stat for %d", pid)
+	}
+	utime, _ := strconv.ParseUint(f[11], 10, 64)
+	stime, _ := strconv.ParseUint(f[12], 10, 64)
+	nice, _ := strconv.Atoi(f[16])
+	start, _ := strconv.ParseUint(f[19], 10, 64)
+	return ProcStat{
+		PID:       pid,
+		Name:      line[open+1 : end],
+		State:     f[0][0],
+		CPUTicks:  utime + stime,
+		Nice:      nice,
+		StartTime: start,
+	}, nil
+}
+
+// SignalProcess sends sig to p, unless its PID now belongs to a different
+// process than the one that was shown.
+func SignalProcess(p ProcStat, sig syscall.Signal) error {
+	cur, err := readProcStat(p.PID)
+	if err != nil || cur.StartTime != p.StartTime {
+		return fmt.Errorf("process %d (%s) has exited", p.PID, p.Name)
+	}
+	return syscall.Kill(p.PID, sig)
+}
+
+// ReniceProcess sets the nice value of p, with the same check as
+// SignalProcess.
+func ReniceProcess(p ProcStat, nice int) error {
+	cur, err := readProcStat(p.PID)
+	if err != nil || cur.StartTime != p.StartTime {
+		return fmt.Errorf("process %d (%s) has exited", p.PID, p.Name)
+	}
+	return syscall.Setpriority(syscall.PRIO_PROCESS, p.PID, nice)
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdbus "github.com/coreos/go-systemd/v22/dbus"
+)
+
+// Unit is a systemd service as the service manager shows it.
+type Unit struct {
+	Name        string
+	Description string
+	LoadState   string // loaded, not-found, masked, ...
+	ActiveState string // active, inactive, failed, activating, ...
+	SubState    string // running, exited, dead, ...
+}
+
+// Running reports whether the service has a process running.
+func (u Unit) Running() bool {
+	return u.ActiveState == "active" && u.SubState == "running"
+}
+
+// Active reports whether the service is active or on its way in or out,
+// so that it can be stopped or restarted rather than started.
+func (u Unit) Active() bool {
+	switch u.ActiveState {
+	case "active", "activating", "deactivating", "reloading":
+		return true
+	}
+	return false
+}
+
+// Failed reports whether the service's last run failed.
+func (u Unit) Failed() bool {
+	return u.ActiveState == "failed"
+}
+
+// Systemd keeps a live list of the services systemd knows about, talking to
+// it over D-Bus on its own goroutine. The list is loaded once and then
+// updated from PropertiesChanged signals. Services the policy hides are
+// left out of Units and Failed. All methods are safe to call from any
+// goroutine; only Action and Details wait for systemd.
+type Systemd struct {
+	mu     sync.Mutex
+	conn   *sdbus.Conn // nil while not connected
+	units  map[string]Unit
+	err    error // why there is no connection
+	policy ServicesConfig
+}
+
+// systemdRetry is how long Run waits before reconnecting, and
+// systemdResync how often it reloads the whole list in case a signal was
+// missed.
+const (
+	systemdRetry  = 5 * time.Second
+	systemdResync = time.Minute
+)
+
+// jobTimeout is how long Action waits for a job to finish. systemd gives
+// up on most jobs by itself well before that.
+const jobTimeout = 2 * time.Minute
+
+// systemd is started from main and read by the service screens.
+var systemd = NewSystemd()
+
+func NewSystemd() *Systemd {
+	return &Systemd{
+		units:  make(map[string]Unit),
+		err:    errors.New("connecting"),
+		policy: DefaultConfig().Services,
+	}
+}
+
+// SetPolicy sets which services are shown and what can be done to them.
+func (s *Systemd) SetPolicy(policy ServicesConfig) {
+	s.mu.Lock()
+	s.policy = policy
+	s.mu.Unlock()
+}
+
+func (s *Systemd) Policy() ServicesConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policy
+}
+
+// Run keeps the list up to date until stop is closed, reconnecting when
+// the connection is lost.
+func (s *Systemd) Run(stop <-chan struct{}) {
+	for {
+		err := s.watch(stop)
+		if err == nil {
+			return
+		}
+		s.mu.Lock()
+		s.conn = nil
+		s.err = err
+		s.mu.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-time.After(systemdRetry):
+		}
+	}
+}
+
+// watch connects and applies changes until stop is closed, which returns
+// nil, or the connection fails.
+func (s *Systemd) watch(stop <-chan struct{}) error {
+	ctx := context.Background()
+	conn, err := sdbus.NewWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.Subscribe(); err != nil {
+		return err
+	}
+	updates := make(chan *sdbus.PropertiesUpdate, 256)
+	overflow := make(chan error, 1)
+	conn.SetPropertiesSubscriber(updates, overflow)
+	if err := s.reload(ctx, conn); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.err = nil
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(systemdResync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case u := <-updates:
+			s.update(ctx, conn, u)
+		case <-overflow:
+			// Updates were dropped, so start over from a full list.
+			if err := s.reload(ctx, conn); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if !conn.Connected() {
+				return errors.New("connection to systemd lost")
+			}
+			if err := s.reload(ctx, conn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// reload replaces the list with every loaded service and every service
+// that has a unit file, so stopped services can be started.
+func (s *Systemd) reload(ctx context.Context, conn *sdbus.Conn) error {
+	statuses, err := conn.ListUnitsByPatternsContext(ctx, nil, []string{"*.service"})
+	if err != nil {
+		return err
+	}
+	units := make(map[string]Unit, len(statuses))
+	for _, st := range statuses {
+		units[st.Name] = unitOf(st)
+	}
+	// Older systemd can't list unit files by pattern; the loaded units
+	// will have to do there.
+	files, err := conn.ListUnitFilesByPatternsContext(ctx, nil, []string{"*.service"})
+	if err == nil {
+		var names []string
+		for _, f := range files {
+			name := path.Base(f.Path)
+			if _, ok := units[name]; !ok && !strings.HasSuffix(name, "@.service") {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			statuses, err := conn.ListUnitsByNamesContext(ctx, names)
+			if err != nil {
+				return err
+			}
+			for _, st := range statuses {
+				if st.LoadState != "not-found" {
+					units[st.Name] = unitOf(st)
+				}
+			}
+		}
+	}
+	s.mu.Lock()
+	s.units = units
+	s.mu.Unlock()
+	return nil
+}
+
+// update applies the changed properties of one unit. Units it hasn't seen
+// yet are looked up in full.
+func (s *Systemd) update(ctx context.Context, conn *sdbus.Conn, u *sdbus.PropertiesUpdate) {
+	if !strings.HasSuffix(u.UnitName, ".service") {
+		return
+	}
+	s.mu.Lock()
+	unit, ok := s.units[u.UnitName]
+	s.mu.Unlock()
+	if !ok {
+		statuses, err := conn.ListUnitsByNamesContext(ctx, []string{u.UnitName})
+		if err != nil || len(statuses) == 0 {
+			return
+		}
+		unit = unitOf(statuses[0])
+	}
+	for prop, v := range u.Changed {
+		value, ok := v.Value().(string)
+		if !ok {
+			continue
+		}
+		switch prop {
+		case "Description":
+			unit.Description = value
+		case "LoadState":
+			unit.LoadState = value
+		case "ActiveState":
+			unit.ActiveState = value
+		case "SubState":
+			unit.SubState = value
+		}
+	}
+	s.mu.Lock()
+	s.units[u.UnitName] = unit
+	s.mu.Unlock()
+}
+
+func unitOf(st sdbus.UnitStatus) Unit {
+	return Unit{
+		Name:        st.Name,
+		Description: st.Description,
+		LoadState:   st.LoadState,
+		ActiveState: st.ActiveState,
+		SubState:    st.SubState,
+	}
+}
+
+// Units returns the visible services by name, and the error that keeps
+// the list from being current, if any.
+func (s *Systemd) Units() ([]Unit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	units := make([]Unit, 0, len(s.units))
+	for _, u := range s.units {
+		if s.policy.Visible(u.Name) {
+			units = append(units, u)
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units, s.err
+}
+
+// Failed returns how many visible services are in the failed state.
+func (s *Systemd) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, u := range s.units {
+		if u.Failed() && s.policy.Visible(u.Name) {
+			n++
+		}
+	}
+	return n
+}
+
+// Action runs a start, stop or restart job for a service and waits for it
+// to finish. It returns an error unless the job's result is "done".
+func (s *Systemd) Action(name, action string) error {
+	s.mu.Lock()
+	conn, err := s.conn, s.err
+	s.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("systemd: %w", err)
+	}
+	var job func(context.Context, string, string, chan<- string) (int, error)
+	switch action {
+	case "start":
+		job = conn.StartUnitContext
+	case "stop":
+		job = conn.StopUnitContext
+	case "restart":
+		job = conn.RestartUnitContext
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+	result := make(chan string, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := job(ctx, name, "replace", result); err != nil {
+		return err
+	}
+	select {
+	case r := <-result:
+		if r != "done" {
+			return fmt.Errorf("%s %s: %s", action, name, r)
+		}
+		return nil
+	case <-time.After(jobTimeout):
+		return fmt.Errorf("%s %s: no result after %v", action, name, jobTimeout)
+	}
+}
+
+// ServiceDetails is what the detail screen shows about a service.
+type ServiceDetails struct {
+	Unit
+	MainPID     uint32        // 0 if no process is running
+	ActiveSince time.Time     // zero unless active
+	Memory      int64         // bytes, -1 without memory accounting
+	CPU         time.Duration // -1 without CPU accounting
+}
+
+// Details asks systemd for the current state and resource usage of a
+// service.
+func (s *Systemd) Details(name string) (ServiceDetails, error) {
+	details := ServiceDetails{Memory: -1, CPU: -1}
+	s.mu.Lock()
+	conn, err := s.conn, s.err
+	s.mu.Unlock()
+	if conn == nil {
+		return details, fmt.Errorf("systemd: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unit, err := conn.GetUnitPropertiesContext(ctx, name)
+	if err != nil {
+		return details, err
+	}
+	service, err := conn.GetUnitTypePropertiesContext(ctx, name, "Service")
+	if err != nil {
+		return details, err
+	}
+	details.Name = name
+	details.Description, _ = unit["Description"].(string)
+	details.LoadState, _ = unit["LoadState"].(string)
+	details.ActiveState, _ = unit["ActiveState"].(string)
+	details.SubState, _ = unit["SubState"].(string)
+	if usec, ok := unit["ActiveEnterTimestamp"].(uint64); ok && usec > 0 && details.ActiveState == "active" {
+		details.ActiveSince = time.UnixMicro(int64(usec))
+	}
+	details.MainPID, _ = service["MainPID"].(uint32)
+	// systemd reports "not available" as the largest uint64.
+	if mem, ok := service["MemoryCurrent"].(uint64); ok && mem != math.MaxUint64 {
+		details.Memory = int64(mem)
+	}
+	if nsec, ok := service["CPUUsageNSec"].(uint64); ok && nsec != math.MaxUint64 {
+		details.CPU = time.Duration(nsec)
+	}
+	return details, nil
+}
+
+// JournalEntry is one line from the journal.
+type JournalEntry struct {
+	Time    time.Time
+	Message string
+}
+
+// GetJournal returns the last n journal entries of a unit, newest first.
+func GetJournal(unit string, n int) ([]JournalEntry, error) {
+	out, err := exec.Command("journalctl", "--unit", unit, "--lines", strconv.Itoa(n),
+		"--output", "json", "--output-fields", "MESSAGE", "--no-pager", "--quiet").Output()
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var raw struct {
+			Time    string          `json:"__REALTIME_TIMESTAMP"`
+			Message json.RawMessage `json:"MESSAGE"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil {
+			continue
+		}
+		entry := JournalEntry{Message: "[binary]"}
+		if usec, err := strconv.ParseInt(raw.Time, 10, 64); err == nil {
+			entry.Time = time.UnixMicro(usec)
+		}
+		// Messages that aren't valid UTF-8 come as arrays of bytes.
+		json.Unmarshal(raw.Message, &entry.Message)
+		entries = append(entries, entry)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// ServiceAction starts, stops or restarts a service and waits for the
+// outcome, if the policy allows it.
+func ServiceAction(service, action string) error {
+	if !systemd.Policy().Allows(service, action) {
+		return fmt.Errorf("%s %s: not allowed", action, service)
+	}
+	return systemd.Action(service, action)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// WirelessInfo is what nl80211 reports about a wireless interface. In
+// station mode the only station is the access point, so Signal and the
+// bitrates are those of our link to it; in AP mode Stations counts the
+// associated clients and Signal is the weakest of them.
+type WirelessInfo struct {
+	SSID      string // "" if not connected
+	AP        bool   // the interface is an access point
+	Signal    int    // dBm, 0 if unknown
+	TxBitrate int    // 100 kbit/s, 0 if unknown
+	RxBitrate int
+	Stations  int
+}
+
+// GetWirelessInfo asks nl80211 about every wireless interface, by name.
+// It returns nil if there are none or the kernel has no nl80211.
+func GetWirelessInfo() map[string]WirelessInfo {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	family, err := conn.GetFamily(unix.NL80211_GENL_NAME)
+	if err != nil {
+		return nil
+	}
+
+	msgs, err := conn.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: unix.NL80211_CMD_GET_INTERFACE, Version: family.Version},
+	}, family.ID, netlink.Request|netlink.Dump)
+	if err != nil {
+		return nil
+	}
+	result := make(map[string]WirelessInfo)
+	for _, msg := range msgs {
+		var name string
+		var index uint32
+		var info WirelessInfo
+		ad, err := netlink.NewAttributeDecoder(msg.Data)
+		if err != nil {
+			continue
+		}
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.NL80211_ATTR_IFNAME:
+				name = ad.String()
+			case unix.NL80211_ATTR_IFINDEX:
+				index = ad.Uint32()
+			case unix.NL80211_ATTR_IFTYPE:
+				info.AP = ad.Uint32() == unix.NL80211_IFTYPE_AP
+			case unix.NL80211_ATTR_SSID:
+				info.SSID = string(ad.Bytes())
+			}
+		}
+		if ad.Err() != nil || name == "" {
+			continue
+		}
+		stations, err := getStations(conn, family, index)
+		if err == nil {
+			info.Stations = len(stations)
+			for i, sta := range stations {
+				if i == 0 || sta.Signal < info.Signal {
+					info.Signal = sta.Signal
+				}
+				if !info.AP {
+					info.TxBitrate, info.RxBitrate = sta.TxBitrate, sta.RxBitrate
+				}
+			}
+		}
+		result[name] = info
+	}
+	return result
+}
+
+type stationInfo struct {
+	Signal               int
+	TxBitrate, RxBitrate int
+}
+
+// getStations dumps the stations of the interface with the given index.
+func getStations(conn *genetlink.Conn, family genetlink.Family, index uint32) ([]stationInfo, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.NL80211_ATTR_IFINDEX, index)
+	data, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := conn.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: unix.NL80211_CMD_GET_STATION, Version: family.Version},
+		Data:   data,
+	}, family.ID, netlink.Request|netlink.Dump)
+	if err != nil {
+		return nil, err
+	}
+	var stations []stationInfo
+	for _, msg := range msgs {
+		ad, err := netlink.NewAttributeDecoder(msg.Data)
+		if err != nil {
+			continue
+		}
+		var sta stationInfo
+		for ad.Next() {
+			if ad.Type() != unix.NL80211_ATTR_STA_INFO {
+				continue
+			}
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case unix.NL80211_STA_INFO_SIGNAL:
+						// A u8 holding a signed value.
+						sta.Signal = int(int8(nad.Uint8()))
+					case unix.NL80211_STA_INFO_TX_BITRATE:
+						nad.Nested(decodeBitrate(&sta.TxBitrate))
+					case unix.NL80211_STA_INFO_RX_BITRATE:
+						nad.Nested(decodeBitrate(&sta.RxBitrate))
+					}
+				}
+				return nil
+			})
+		}
+		if ad.Err() == nil {
+			stations = append(stations, sta)
+		}
+	}
+	return stations, nil
+}
+
+// decodeBitrate reads a nested rate info into rate, in 100 kbit/s. The
+// 16-bit attribute is only there for rates that fit in it.
+func decodeBitrate(rate *int) func(*netlink.AttributeDecoder) error {
+	return func(ad *netlink.AttributeDecoder) error {
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.NL80211_RATE_INFO_BITRATE32:
+				*rate = int(ad.Uint32())
+			case unix.NL80211_RATE_INFO_BITRATE:
+				if *rate == 0 {
+					*rate = int(ad.Uint16())
+				}
+			}
+		}
+		return nil
+	}
+}